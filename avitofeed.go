@@ -4,188 +4,618 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
-	"log"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type AvitoFeed struct {
-	LastModified  time.Time
-	XMLName       xml.Name `xml:"Ads"`
-	FormatVersion int      `xml:"formatVersion,attr"`
-	Target        string   `xml:"target,attr"`
-	Ad            []Ad     `xml:"Ad"`
+	LastModified  time.Time `xml:"-"`
+	XMLName       xml.Name  `xml:"Ads"`
+	FormatVersion int       `xml:"formatVersion,attr,omitempty"`
+	Target        string    `xml:"target,attr,omitempty"`
+	Ad            []Ad      `xml:"Ad,omitempty"`
+
+	Unknown     []UnknownElement `xml:",any"`
+	UnknownAttr []xml.Attr       `xml:",any,attr"`
+	Strict      bool             `xml:"-"` // Check also reports unknown elements and attributes
 }
 
 type Ad struct {
-	ID              string  `xml:"Id"`
-	AdStatus        string  `xml:"AdStatus"`
-	AllowEmail      string  `xml:"AllowEmail"`
-	ContactPhone    string  `xml:"ContactPhone"`
-	Latitude        string  `xml:"Latitude"`
-	Longitude       string  `xml:"Longitude"`
-	Description     string  `xml:"Description"`
-	Category        string  `xml:"Category"`
-	OperationType   string  `xml:"OperationType"`
-	Price           int64   `xml:"Price"`
-	Rooms           string  `xml:"Rooms"`
-	Square          float32 `xml:"Square"`
-	BalconyOrLoggia string  `xml:"BalconyOrLoggia"`
-	KitchenSpace    float32 `xml:"KitchenSpace"`
-	ViewFromWindows string  `xml:"ViewFromWindows"`
-	CeilingHeight   string  `xml:"CeilingHeight"`
-	LivingSpace     float32 `xml:"LivingSpace"`
-	Decoration      string  `xml:"Decoration"`
-	DealType        string  `xml:"DealType"`
+	ID              string  `xml:"Id,omitempty"`
+	AdStatus        string  `xml:"AdStatus,omitempty"`
+	AllowEmail      string  `xml:"AllowEmail,omitempty"`
+	ContactPhone    string  `xml:"ContactPhone,omitempty"`
+	Latitude        string  `xml:"Latitude,omitempty"`
+	Longitude       string  `xml:"Longitude,omitempty"`
+	Description     string  `xml:"Description,omitempty"`
+	Category        string  `xml:"Category,omitempty"`
+	OperationType   string  `xml:"OperationType,omitempty"`
+	Price           int64   `xml:"Price,omitempty"`
+	Rooms           string  `xml:"Rooms,omitempty"`
+	Square          float32 `xml:"Square,omitempty"`
+	BalconyOrLoggia string  `xml:"BalconyOrLoggia,omitempty"`
+	KitchenSpace    float32 `xml:"KitchenSpace,omitempty"`
+	ViewFromWindows string  `xml:"ViewFromWindows,omitempty"`
+	CeilingHeight   string  `xml:"CeilingHeight,omitempty"`
+	LivingSpace     float32 `xml:"LivingSpace,omitempty"`
+	Decoration      string  `xml:"Decoration,omitempty"`
+	DealType        string  `xml:"DealType,omitempty"`
 	RoomType        struct {
-		Option string `xml:"Option"`
-	} `xml:"RoomType"`
-	Status           string `xml:"Status"`
-	Floor            int64  `xml:"Floor"`
-	Floors           int64  `xml:"Floors"`
-	HouseType        string `xml:"HouseType"`
-	MarketType       string `xml:"MarketType"`
-	PropertyRights   string `xml:"PropertyRights"`
-	NewDevelopmentId string `xml:"NewDevelopmentId"`
-	Images           struct {
-		Image []struct {
-			URL string `xml:"url,attr"`
-		} `xml:"Image"`
-	} `xml:"Images"`
+		Option string `xml:"Option,omitempty"`
+	} `xml:"RoomType,omitempty"`
+	Status           string `xml:"Status,omitempty"`
+	Floor            int64  `xml:"Floor,omitempty"`
+	Floors           int64  `xml:"Floors,omitempty"`
+	HouseType        string `xml:"HouseType,omitempty"`
+	MarketType       string `xml:"MarketType,omitempty"`
+	PropertyRights   string `xml:"PropertyRights,omitempty"`
+	NewDevelopmentId string `xml:"NewDevelopmentId,omitempty"`
+	Address          string `xml:"Address,omitempty"`
+
+	ObjectType          string  `xml:"ObjectType,omitempty"`
+	ObjectSubtype       string  `xml:"ObjectSubtype,omitempty"`
+	BuildingType        string  `xml:"BuildingType,omitempty"`
+	LeaseType           string  `xml:"LeaseType,omitempty"`
+	LeaseDeposit        string  `xml:"LeaseDeposit,omitempty"`
+	LeaseCommissionSize string  `xml:"LeaseCommissionSize,omitempty"`
+	LandArea            float32 `xml:"LandArea,omitempty"`
+	LandStatus          string  `xml:"LandStatus,omitempty"`
+	WallsType           string  `xml:"WallsType,omitempty"`
+	DistanceToCity      int64   `xml:"DistanceToCity,omitempty"`
+	Secured             string  `xml:"Secured,omitempty"`
+	ParkingType         string  `xml:"ParkingType,omitempty"`
+	Entrance            string  `xml:"Entrance,omitempty"`
+	Heating             string  `xml:"Heating,omitempty"`
+	Images              struct {
+		Image []AvitoImage `xml:"Image,omitempty"`
+	} `xml:"Images,omitempty"`
+
+	Unknown     []UnknownElement `xml:",any"`
+	UnknownAttr []xml.Attr       `xml:",any,attr"`
+}
+
+type AvitoImage struct {
+	URL string `xml:"url,attr,omitempty"`
 }
 
+// avitoCategory is the value of Ad.Category.
+type avitoCategory string
+
+const (
+	avitoCategoryFlat       avitoCategory = "Квартиры"
+	avitoCategoryRoom       avitoCategory = "Комнаты"
+	avitoCategoryHouse      avitoCategory = "Дома, дачи, коттеджи"
+	avitoCategoryLand       avitoCategory = "Земельные участки"
+	avitoCategoryGarage     avitoCategory = "Гаражи и машиноместа"
+	avitoCategoryCommercial avitoCategory = "Коммерческая недвижимость"
+	// avitoCategoryStorage is not an Avito category: storage rooms are published as garages or
+	// commercial property with ObjectType "Кладовая" and validated by CategoryType against their own rules.
+	avitoCategoryStorage avitoCategory = "Кладовые"
+)
+
+// avitoOperationType is the value of Ad.OperationType.
+type avitoOperationType string
+
+const (
+	avitoOperationTypeSale avitoOperationType = "Продам"
+	avitoOperationTypeRent avitoOperationType = "Сдам"
+)
+
+// avitoMarketType is the value of Ad.MarketType.
+type avitoMarketType string
+
+const (
+	avitoMarketTypeNew       avitoMarketType = "Новостройка"
+	avitoMarketTypeSecondary avitoMarketType = "Вторичка"
+)
+
+// avitoDecoration is the value of Ad.Decoration.
+type avitoDecoration string
+
+const (
+	avitoDecorationNone    avitoDecoration = "Без отделки"
+	avitoDecorationPreFine avitoDecoration = "Предчистовая"
+	avitoDecorationFine    avitoDecoration = "Чистовая"
+)
+
+// avitoHouseType is the value of Ad.HouseType.
+type avitoHouseType string
+
+const (
+	avitoHouseTypeBrick         avitoHouseType = "Кирпичный"
+	avitoHouseTypePanel         avitoHouseType = "Панельный"
+	avitoHouseTypeBlock         avitoHouseType = "Блочный"
+	avitoHouseTypeMonolith      avitoHouseType = "Монолитный"
+	avitoHouseTypeMonolithBrick avitoHouseType = "Монолитно-кирпичный"
+	avitoHouseTypeWood          avitoHouseType = "Деревянный"
+)
+
+const (
+	avitoObjectTypeParking = "Машиноместо"
+	avitoObjectTypeStorage = "Кладовая"
+)
+
+// The enums accept the values of the tables the ads are converted with, except the categories,
+// whose table also holds avitoCategoryStorage.
+var (
+	avitoCategoryEnum = Enum{Values: enumValues(avitoCategoryFlat, avitoCategoryRoom, avitoCategoryHouse,
+		avitoCategoryLand, avitoCategoryGarage, avitoCategoryCommercial)}
+	avitoOperationTypeEnum = Enum{Values: enumValues(avitoOperationTypes.values()...)}
+	avitoMarketTypeEnum    = Enum{Values: enumValues(avitoMarketTypeNew, avitoMarketTypeSecondary)}
+	avitoDecorationEnum    = Enum{Values: enumValues(avitoDecorations.values()...)}
+	avitoHouseTypeEnum     = Enum{Values: enumValues(avitoHouseTypes.values()...)}
+)
+
+// CategoryType returns the Avito category the ad is validated against.
+// Storage rooms published as a commercial or garage ObjectType are reported as avitoCategoryStorage.
+func (a *Ad) CategoryType() string {
+	category := strings.TrimSpace(a.Category)
+	switch avitoCategory(category) {
+	case avitoCategoryGarage, avitoCategoryCommercial:
+		if strings.EqualFold(strings.TrimSpace(a.ObjectType), avitoObjectTypeStorage) {
+			return string(avitoCategoryStorage)
+		}
+	}
+	return category
+}
+
+// Get downloads the feed with DefaultFetcher.
 func (f *AvitoFeed) Get(url string) (err error) {
-	resp, err := GetResponse(url)
-	if err != nil {
-		return err
+	_, err = DefaultFetcher.FetchFeed(f, url)
+	return err
+}
+
+// Marshal returns the feed as Avito XML.
+func (f *AvitoFeed) Marshal() ([]byte, error) {
+	return marshalFeed(f, map[string]bool{"Description": true})
+}
+
+// WriteTo writes the feed as Avito XML to w.
+func (f *AvitoFeed) WriteTo(w io.Writer) (n int64, err error) {
+	return writeFeed(w, f, map[string]bool{"Description": true})
+}
+
+// Check runs the enabled Avito rules of DefaultRules.
+func (f *AvitoFeed) Check() (results []string) {
+	return DefaultRules.Check(f)
+}
+
+// CheckWith is Check with options, e.g. checking the feed as of another date.
+func (f *AvitoFeed) CheckWith(options CheckOptions) (results []string) {
+	return DefaultRules.CheckWith(f, options)
+}
+
+var avitoRules = []Rule{
+	gateRule(FeedRule("avito/feed-size", func(f *AvitoFeed, results *Results) {
+		if len(f.Ad) < 2 {
+			results.Add("", emptyFeed)
+			return
+		}
+		if len(f.Ad) <= 10 {
+			results.Add("", fmt.Sprintf("feed contains only %v items", len(f.Ad)))
+		}
+	})),
+	freshnessRule[*AvitoFeed](PlatformAvito),
+	lotRule("avito/ids", func(lot Ad, run *checkRun, results *Results) {
+		checkStringWithPos(results.lot, "Ad", "ID", lot.ID, results)
+	}),
+	LotRule("avito/required", func(lot Ad, results *Results) {
+		id := lot.ID
+		checkStringWithID(id, "Ad", "ContactPhone", lot.ContactPhone, results)
+		checkStringWithID(id, "Ad", "Description", lot.Description, results)
+		checkStringWithID(id, "Ad", "Category", lot.Category, results)
+		checkZeroWithID(id, "Ad", "Price", int(lot.Price), results)
+		checkStringWithID(id, "Ad", "OperationType", lot.OperationType, results)
+	}),
+	LotRule("avito/enums", func(lot Ad, results *Results) {
+		id := lot.ID
+		checkEnumWithID(id, "Ad", "Category", strings.TrimSpace(lot.Category), avitoCategoryEnum, results)
+		checkEnumWithID(id, "Ad", "OperationType", lot.OperationType, avitoOperationTypeEnum, results)
+		checkEnumWithID(id, "Ad", "Decoration", lot.Decoration, avitoDecorationEnum, results)
+		checkEnumWithID(id, "Ad", "HouseType", lot.HouseType, avitoHouseTypeEnum, results)
+	}),
+	LotRule("avito/category", func(lot Ad, results *Results) {
+		switch avitoCategory(lot.CategoryType()) {
+		case avitoCategoryFlat:
+			checkAvitoFlat(lot, results)
+		case avitoCategoryRoom:
+			checkAvitoRoom(lot, results)
+		case avitoCategoryHouse:
+			checkAvitoHouse(lot, results)
+		case avitoCategoryLand:
+			checkAvitoLand(lot, results)
+		case avitoCategoryGarage:
+			checkAvitoGarage(lot, results)
+		case avitoCategoryCommercial:
+			checkAvitoCommercial(lot, results)
+		case avitoCategoryStorage:
+			checkAvitoStorage(lot, results)
+		}
+	}),
+	LotRule("avito/images", func(lot Ad, results *Results) {
+		for idx, image := range lot.Images.Image {
+			checkStringWithPos(idx, "Images.Image", "URL", image.URL, results)
+		}
+		if len(lot.Images.Image) < 3 || len(lot.Images.Image) > 40 {
+			results.Add("Ad.Images.Image", fmt.Sprintf("field Images.Image contains '%v' items. InternalID: %v", len(lot.Images.Image), lot.ID))
+		}
+	}),
+	FeedRule("avito/building-consistency", func(f *AvitoFeed, results *Results) {
+		var groups buildingGroups
+		for idx, lot := range f.Ad {
+			if lot.NewDevelopmentId == "" {
+				continue
+			}
+			groups.add(lot.NewDevelopmentId, lot.NewDevelopmentId, buildingLot{
+				id:       lot.ID,
+				position: idx,
+				values: map[string]string{
+					"Ad.Floors":    formatBuildingInt(lot.Floors),
+					"Ad.HouseType": lot.HouseType,
+					"Ad.Address":   strings.Join(strings.Fields(lot.Address), " "),
+				},
+				latitude:  lot.Latitude,
+				longitude: lot.Longitude,
+			})
+		}
+		groups.check([]string{"Ad.Floors", "Ad.HouseType", "Ad.Address"}, results)
+		groups.checkCoordinates("Ad.Coordinates", results)
+	}),
+	feedRule("avito/descriptions", func(f *AvitoFeed, run *checkRun, results *Results) {
+		ids := make([]string, len(f.Ad))
+		descriptions := make([]string, len(f.Ad))
+		for idx, lot := range f.Ad {
+			ids[idx], descriptions[idx] = lot.ID, lot.Description
+		}
+		checkDescriptions(run.options.descriptionRule(PlatformAvito), "Ad", "Description", ids, descriptions, results)
+	}),
+	FeedRule("avito/unknown", func(f *AvitoFeed, results *Results) {
+		if f.Strict {
+			f.checkUnknown(results)
+		}
+	}),
+}
+
+// CheckUnknown reports elements and attributes of the feed and its ads that are not modelled by AvitoFeed.
+func (f *AvitoFeed) CheckUnknown() (results []string) {
+	unknown := &Results{lot: -1}
+	f.checkUnknown(unknown)
+	return unknown.messages()
+}
+
+func (f *AvitoFeed) checkUnknown(results *Results) {
+	checkUnknownWithID("", -1, "Ads", f.Unknown, f.UnknownAttr, results)
+	for idx, lot := range f.Ad {
+		checkUnknownWithID(lot.ID, idx, "Ad", lot.Unknown, lot.UnknownAttr, results)
 	}
-	defer resp.Body.Close()
-	err = statusCodeHandler(resp)
-	if err != nil {
-		return err
+}
+
+// DiscardUnknown drops the unmodelled elements and attributes, so Marshal writes only known fields.
+func (f *AvitoFeed) DiscardUnknown() {
+	f.Unknown, f.UnknownAttr = nil, nil
+	for i := range f.Ad {
+		f.Ad[i].Unknown, f.Ad[i].UnknownAttr = nil, nil
 	}
+}
 
-	AttributeLastModified := resp.Header.Get("Last-Modified")
-	if AttributeLastModified != "" {
-		lastModifiedDate, err := time.Parse(time.RFC1123, resp.Header.Get("Last-Modified"))
-		if err != nil {
-			return err
-		}
-		f.LastModified = lastModifiedDate
-	} else {
-		log.Println("Header not contains `Last-Modified`")
+func checkAvitoFlat(lot Ad, results *Results) {
+	id := lot.ID
+
+	checkStringWithID(id, "Ad", "MarketType", lot.MarketType, results)
+	checkStringWithID(id, "Ad", "HouseType", lot.HouseType, results)
+	checkZeroWithID(id, "Ad", "Floor", int(lot.Floor), results)
+	checkZeroWithID(id, "Ad", "Floors", int(lot.Floors), results)
+	checkStringWithID(id, "Ad", "Rooms", lot.Rooms, results)
+	checkZeroWithID(id, "Ad", "Square", lot.Square, results)
+
+	if lot.LivingSpace == 0 && lot.Rooms != "Студия" {
+		results.Add("Ad.LivingSpace", fmt.Sprintf("field LivingSpace is empty. InternalID: %v", lot.ID))
 	}
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
+
+	switch avitoMarketType(lot.MarketType) {
+	case avitoMarketTypeNew:
+		checkStringWithID(id, "Ad", "Status", lot.Status, results)
+		checkStringWithID(id, "Ad", "NewDevelopmentId", lot.NewDevelopmentId, results)
+		checkStringWithID(id, "Ad", "PropertyRights", lot.PropertyRights, results)
+		checkStringWithID(id, "Ad", "Decoration", lot.Decoration, results)
+	default:
+		checkEnumWithID(id, "Ad", "MarketType", lot.MarketType, avitoMarketTypeEnum, results)
 	}
 
-	err = xml.Unmarshal(responseBody, &f)
-	if err != nil {
-		return err
+	if avitoOperationType(lot.OperationType) == avitoOperationTypeRent {
+		checkStringWithID(id, "Ad", "LeaseType", lot.LeaseType, results)
+	}
+
+	if lot.Floor > lot.Floors {
+		results.Add("Ad.Floor", fmt.Sprintf("field Floor is bigger than Floors. InternalID: %v", lot.ID))
 	}
-	//TODO Исправить значение f.LastModified
-	return nil
 }
 
-func (f *AvitoFeed) Check() (results []string) {
+func checkAvitoRoom(lot Ad, results *Results) {
+	id := lot.ID
+
+	checkStringWithID(id, "Ad", "HouseType", lot.HouseType, results)
+	checkZeroWithID(id, "Ad", "Floor", int(lot.Floor), results)
+	checkZeroWithID(id, "Ad", "Floors", int(lot.Floors), results)
+	checkStringWithID(id, "Ad", "Rooms", lot.Rooms, results)
+	checkZeroWithID(id, "Ad", "Square", lot.Square, results)
 
-	if len(f.Ad) < 2 {
-		results = append(results, emptyFeed)
-		return results
+	if avitoOperationType(lot.OperationType) == avitoOperationTypeRent {
+		checkStringWithID(id, "Ad", "LeaseType", lot.LeaseType, results)
 	}
 
-	if len(f.Ad) <= 10 {
-		results = append(results, fmt.Sprintf("feed contains only %v items", len(f.Ad)))
-		return results
+	if lot.Floor > lot.Floors {
+		results.Add("Ad.Floor", fmt.Sprintf("field Floor is bigger than Floors. InternalID: %v", lot.ID))
 	}
+}
 
-	for idx, lot := range f.Ad {
-		checkStringWithPos(idx, "Ad", "ID", lot.ID, &results)
-		id := lot.ID
-		checkStringWithID(id, "Ad", "ContactPhone", lot.ContactPhone, &results)
-		checkStringWithID(id, "Ad", "Description", lot.Description, &results)
-		checkStringWithID(id, "Ad", "Category", lot.Category, &results)
-		checkZeroWithID(id, "Ad", "Price", int(lot.Price), &results)
-		checkStringWithID(id, "Ad", "OperationType", lot.OperationType, &results)
-		checkStringWithID(id, "Ad", "MarketType", lot.MarketType, &results)
-		checkStringWithID(id, "Ad", "HouseType", lot.HouseType, &results)
-		checkZeroWithID(id, "Ad", "Floor", int(lot.Floor), &results)
-		checkZeroWithID(id, "Ad", "Floors", int(lot.Floors), &results)
-		checkStringWithID(id, "Ad", "Rooms", lot.Rooms, &results)
-		checkZeroWithID(id, "Ad", "Square", lot.Square, &results)
-
-		if lot.LivingSpace == 0 && lot.Rooms != "Студия" {
-			results = append(results, fmt.Sprintf("field LivingSpace is empty. InternalID: %v", lot.ID))
-		}
-
-		checkStringWithID(id, "Ad", "Status", lot.Status, &results)
-		checkStringWithID(id, "Ad", "NewDevelopmentId", lot.NewDevelopmentId, &results)
-		checkStringWithID(id, "Ad", "PropertyRights", lot.PropertyRights, &results)
-		checkStringWithID(id, "Ad", "Decoration", lot.Decoration, &results)
-
-		if lot.Floor > lot.Floors {
-			results = append(results, fmt.Sprintf("field Floor is bigger than Floors. InternalID: %v", lot.ID))
-		}
-		for idx, image := range lot.Images.Image {
-			checkStringWithPos(idx, "Images.Image", "URL", image.URL, &results)
-		}
+func checkAvitoHouse(lot Ad, results *Results) {
+	id := lot.ID
 
-		if len(lot.Images.Image) < 3 || len(lot.Images.Image) > 40 {
-			results = append(results, fmt.Sprintf("field Images.Image contains '%v' items. InternalID: %v", len(lot.Images.Image), lot.ID))
-		}
+	checkStringWithID(id, "Ad", "ObjectType", lot.ObjectType, results)
+	checkZeroWithID(id, "Ad", "Square", lot.Square, results)
+	checkZeroWithID(id, "Ad", "LandArea", lot.LandArea, results)
+	checkZeroWithID(id, "Ad", "Floors", int(lot.Floors), results)
+	checkStringWithID(id, "Ad", "WallsType", lot.WallsType, results)
+
+	if avitoOperationType(lot.OperationType) == avitoOperationTypeRent {
+		checkStringWithID(id, "Ad", "LeaseType", lot.LeaseType, results)
+	}
+}
+
+func checkAvitoLand(lot Ad, results *Results) {
+	id := lot.ID
+
+	checkStringWithID(id, "Ad", "ObjectType", lot.ObjectType, results)
+	checkZeroWithID(id, "Ad", "LandArea", lot.LandArea, results)
+}
+
+func checkAvitoGarage(lot Ad, results *Results) {
+	id := lot.ID
+
+	checkStringWithID(id, "Ad", "ObjectType", lot.ObjectType, results)
+	checkStringWithID(id, "Ad", "ObjectSubtype", lot.ObjectSubtype, results)
+	if lot.ObjectType != avitoObjectTypeParking {
+		checkZeroWithID(id, "Ad", "Square", lot.Square, results)
+	}
+	checkStringWithID(id, "Ad", "Secured", lot.Secured, results)
+}
+
+func checkAvitoCommercial(lot Ad, results *Results) {
+	id := lot.ID
+
+	checkStringWithID(id, "Ad", "ObjectType", lot.ObjectType, results)
+	checkZeroWithID(id, "Ad", "Square", lot.Square, results)
+	checkStringWithID(id, "Ad", "BuildingType", lot.BuildingType, results)
+	checkStringWithID(id, "Ad", "PropertyRights", lot.PropertyRights, results)
+
+	if lot.Floors != 0 && lot.Floor > lot.Floors {
+		results.Add("Ad.Floor", fmt.Sprintf("field Floor is bigger than Floors. InternalID: %v", lot.ID))
+	}
+}
+
+func checkAvitoStorage(lot Ad, results *Results) {
+	id := lot.ID
+
+	checkZeroWithID(id, "Ad", "Square", lot.Square, results)
+	if lot.NewDevelopmentId == "" {
+		checkStringWithID(id, "Ad", "Address", lot.Address, results)
 	}
-	return results
 }
 
 type AvitoDevelopments struct {
-	Region []AvitoRegion `xml:"Region"`
+	// LastModified is the Last-Modified header of the developments catalog, zero when unknown.
+	LastModified time.Time     `xml:"-"`
+	Region       []AvitoRegion `xml:"Region,omitempty"`
 }
 
 type AvitoRegion struct {
-	Name string      `xml:"name,attr"`
-	City []AvitoCity `xml:"City"`
+	Name string      `xml:"name,attr,omitempty"`
+	City []AvitoCity `xml:"City,omitempty"`
 }
 
 type AvitoCity struct {
-	Name   string        `xml:"name,attr"`
-	Object []AvitoObject `xml:"Object"`
+	Name   string        `xml:"name,attr,omitempty"`
+	Object []AvitoObject `xml:"Object,omitempty"`
 }
 
 type AvitoObject struct {
-	ID        string       `xml:"id,attr"`
-	Name      string       `xml:"name,attr"`
-	Address   string       `xml:"address,attr"`
-	Developer string       `xml:"developer,attr"`
-	Housing   []AvitoHouse `xml:"Housing"`
+	ID        string       `xml:"id,attr,omitempty"`
+	Name      string       `xml:"name,attr,omitempty"`
+	Address   string       `xml:"address,attr,omitempty"`
+	Developer string       `xml:"developer,attr,omitempty"`
+	Housing   []AvitoHouse `xml:"Housing,omitempty"`
 }
 
 type AvitoHouse struct {
-	ID      string `xml:"id,attr"`
-	Name    string `xml:"name,attr"`
-	Address string `xml:"address,attr"`
+	ID      string `xml:"id,attr,omitempty"`
+	Name    string `xml:"name,attr,omitempty"`
+	Address string `xml:"address,attr,omitempty"`
 }
 
+// avitoDevelopmentsURL is the Avito catalog of new developments.
+var avitoDevelopmentsURL = "https://autoload.avito.ru/format/New_developments.xml"
+
+// GetDevelopments downloads the Avito catalog of new developments. It is dated with its own LastModified;
+// the LastModified of the feed is left as is.
 func (f *AvitoFeed) GetDevelopments() (developments AvitoDevelopments, err error) {
-	url := "https://autoload.avito.ru/format/New_developments.xml"
-	resp, err := GetResponse(url)
-	defer resp.Body.Close()
+	result, err := DefaultFetcher.Fetch(&developments, avitoDevelopmentsURL)
 	if err != nil {
 		return developments, err
 	}
+	developments.LastModified = result.LastModified
+	return developments, nil
+}
 
-	err = statusCodeHandler(resp)
-	if err != nil {
-		return developments, err
+// avitoCategories maps the values of Ad.CategoryType.
+var avitoCategories = enumTable[avitoCategory, LotCategory]{
+	{avitoCategoryFlat, LotCategoryFlat},
+	{avitoCategoryRoom, LotCategoryRoom},
+	{avitoCategoryHouse, LotCategoryHouse},
+	{avitoCategoryLand, LotCategoryLand},
+	{avitoCategoryGarage, LotCategoryGarage},
+	{avitoCategoryCommercial, LotCategoryCommercial},
+	{avitoCategoryStorage, LotCategoryStorage},
+}
+
+var avitoOperationTypes = enumTable[avitoOperationType, DealType]{
+	{avitoOperationTypeSale, DealTypeSale},
+	{avitoOperationTypeRent, DealTypeRent},
+}
+
+var avitoDecorations = enumTable[avitoDecoration, Renovation]{
+	{avitoDecorationNone, RenovationNone},
+	{avitoDecorationPreFine, RenovationPreFine},
+	{avitoDecorationFine, RenovationFine},
+}
+
+var avitoBalconies = enumTable[string, Balcony]{
+	{"Балкон", BalconyBalcony},
+	{"Лоджия", BalconyLoggia},
+}
+
+var avitoHouseTypes = enumTable[avitoHouseType, BuildingMaterial]{
+	{avitoHouseTypeBrick, BuildingMaterialBrick},
+	{avitoHouseTypeMonolith, BuildingMaterialMonolith},
+	{avitoHouseTypeMonolithBrick, BuildingMaterialMonolithBrick},
+	{avitoHouseTypePanel, BuildingMaterialPanel},
+	{avitoHouseTypeBlock, BuildingMaterialBlock},
+	{avitoHouseTypeWood, BuildingMaterialWood},
+}
+
+const (
+	avitoRoomsStudio   = "Студия"
+	avitoRoomsOpenPlan = "Своб. планировка"
+	avitoRoomsMany     = "10 и более"
+)
+
+// Lots returns the ads of the feed as canonical lots.
+func (f *AvitoFeed) Lots() (lots []Lot, results []string) {
+	for _, ad := range f.Ad {
+		lot := Lot{
+			Source:        PlatformAvito,
+			ID:            ad.ID,
+			NewBuilding:   avitoMarketType(ad.MarketType) == avitoMarketTypeNew,
+			Apartments:    ad.Status == "Апартаменты",
+			Description:   ad.Description,
+			Phone:         ad.ContactPhone,
+			Address:       ad.Address,
+			Latitude:      parseFloat(ad.Latitude),
+			Longitude:     parseFloat(ad.Longitude),
+			Price:         float64(ad.Price),
+			Currency:      "RUB",
+			Area:          float64(ad.Square),
+			LivingArea:    float64(ad.LivingSpace),
+			KitchenArea:   float64(ad.KitchenSpace),
+			CeilingHeight: parseFloat(ad.CeilingHeight),
+			Floor:         int(ad.Floor),
+			FloorsTotal:   int(ad.Floors),
+			BuildingID:    ad.NewDevelopmentId,
+		}
+		lot.Category = mapFromPlatform(avitoCategories, ad.ID, "Ad.Category", ad.CategoryType(), &results)
+		lot.Deal = mapFromPlatform(avitoOperationTypes, ad.ID, "Ad.OperationType", ad.OperationType, &results)
+		lot.Renovation = mapFromPlatform(avitoDecorations, ad.ID, "Ad.Decoration", ad.Decoration, &results)
+		lot.Balcony = mapFromPlatform(avitoBalconies, ad.ID, "Ad.BalconyOrLoggia", ad.BalconyOrLoggia, &results)
+		lot.Material = mapFromPlatform(avitoHouseTypes, ad.ID, "Ad.HouseType", ad.HouseType, &results)
+
+		switch ad.Rooms {
+		case "":
+		case avitoRoomsStudio:
+			lot.Studio = true
+		case avitoRoomsOpenPlan:
+			lot.OpenPlan = true
+		case avitoRoomsMany:
+			lot.Rooms = 10
+		default:
+			rooms, err := strconv.Atoi(ad.Rooms)
+			if err != nil {
+				results = append(results, fmt.Sprintf("field Ad.Rooms value '%v' can not be mapped. InternalID: %v", ad.Rooms, ad.ID))
+			}
+			lot.Rooms = rooms
+		}
+
+		for _, image := range ad.Images.Image {
+			lot.Images = append(lot.Images, image.URL)
+		}
+		lots = append(lots, lot)
 	}
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return developments, err
+	return lots, results
+}
+
+// FromLots replaces the ads of the feed with the given lots.
+func (f *AvitoFeed) FromLots(lots []Lot) (results []string) {
+	if f.FormatVersion == 0 {
+		f.FormatVersion = 3
+	}
+	if f.Target == "" {
+		f.Target = "Avito.ru"
 	}
 
-	err = xml.Unmarshal(responseBody, &developments)
-	if err != nil {
-		return developments, err
+	f.Ad = nil
+	for _, lot := range lots {
+		ad := Ad{
+			ID:            lot.ID,
+			ContactPhone:  lot.Phone,
+			Description:   lot.Description,
+			Address:       lot.Address,
+			Latitude:      formatFloat(lot.Latitude),
+			Longitude:     formatFloat(lot.Longitude),
+			Price:         int64(lot.Price),
+			Square:        float32(lot.Area),
+			LivingSpace:   float32(lot.LivingArea),
+			KitchenSpace:  float32(lot.KitchenArea),
+			CeilingHeight: formatFloat(lot.CeilingHeight),
+			Floor:         int64(lot.Floor),
+			Floors:        int64(lot.FloorsTotal),
+		}
+		ad.Category = mapToPlatform(avitoCategories, PlatformAvito, lot.ID, "Category", lot.Category, &results)
+		if lot.Category == LotCategoryStorage {
+			ad.Category = string(avitoCategoryGarage)
+			ad.ObjectType = avitoObjectTypeStorage
+		}
+		ad.OperationType = mapToPlatform(avitoOperationTypes, PlatformAvito, lot.ID, "Deal", lot.Deal, &results)
+		ad.Decoration = mapToPlatform(avitoDecorations, PlatformAvito, lot.ID, "Renovation", lot.Renovation, &results)
+		if lot.Balcony != BalconyNone {
+			ad.BalconyOrLoggia = mapToPlatform(avitoBalconies, PlatformAvito, lot.ID, "Balcony", lot.Balcony, &results)
+		}
+		ad.HouseType = mapToPlatform(avitoHouseTypes, PlatformAvito, lot.ID, "Material", lot.Material, &results)
+		ad.NewDevelopmentId = copyCatalogID(lot, PlatformAvito, "BuildingID", lot.BuildingID, &results)
+
+		if lot.Currency != "" && !strings.EqualFold(lot.Currency, "RUB") && !strings.EqualFold(lot.Currency, "RUR") {
+			results = append(results, fmt.Sprintf("field Currency value '%v' can not be mapped to %s. InternalID: %v", lot.Currency, PlatformAvito, lot.ID))
+		}
+
+		if lot.Category == LotCategoryFlat || lot.Category == LotCategoryRoom {
+			switch {
+			case lot.Studio:
+				ad.Rooms = avitoRoomsStudio
+			case lot.OpenPlan:
+				ad.Rooms = avitoRoomsOpenPlan
+			case lot.Rooms >= 10:
+				ad.Rooms = avitoRoomsMany
+			case lot.Rooms > 0:
+				ad.Rooms = strconv.Itoa(lot.Rooms)
+			}
+		}
+
+		if lot.Category == LotCategoryFlat {
+			if lot.NewBuilding {
+				ad.MarketType = string(avitoMarketTypeNew)
+				ad.PropertyRights = "Застройщик"
+				ad.Status = "Квартира"
+				if lot.Apartments {
+					ad.Status = "Апартаменты"
+				}
+			} else {
+				ad.MarketType = string(avitoMarketTypeSecondary)
+			}
+		}
+
+		for _, url := range append(append(append([]string{}, lot.Images...), lot.PlanImages...), lot.FloorPlanImages...) {
+			ad.Images.Image = append(ad.Images.Image, AvitoImage{URL: url})
+		}
+		f.Ad = append(f.Ad, ad)
 	}
-	//TODO Исправить значение f.LastModified
-	return developments, err
+	return results
 }
@@ -0,0 +1,109 @@
+package price_placements_feeds
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetDevelopmentsKeepsFeedDate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", "Mon, 13 May 2024 06:00:00 GMT")
+		w.Write([]byte(`<Developments><Region name="Москва"><City name="Москва"><Object id="1001" name="ЖК Парк"/></City></Region></Developments>`))
+	}))
+	defer server.Close()
+	defer func(url string) { avitoDevelopmentsURL = url }(avitoDevelopmentsURL)
+	avitoDevelopmentsURL = server.URL
+
+	feed := &AvitoFeed{LastModified: testNow}
+	developments, err := feed.GetDevelopments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, time.May, 13, 6, 0, 0, 0, time.UTC); !developments.LastModified.Equal(want) {
+		t.Errorf("developments LastModified = %s, want %s", developments.LastModified, want)
+	}
+	if !feed.LastModified.Equal(testNow) {
+		t.Errorf("feed LastModified changed to %s", feed.LastModified)
+	}
+	if len(developments.Region) != 1 || developments.Region[0].City[0].Object[0].ID != "1001" {
+		t.Errorf("developments = %+v", developments)
+	}
+}
+
+// avitoCategoryFields returns the fields reported by avito/category, by ad ID.
+func avitoCategoryFields(feed *AvitoFeed) map[string][]string {
+	fields := make(map[string][]string)
+	for _, issue := range DefaultRules.Only("avito/category").CheckIssues(feed, CheckOptions{}) {
+		fields[issue.LotID] = append(fields[issue.LotID], issue.Field)
+	}
+	return fields
+}
+
+func TestAvitoCategoryChecks(t *testing.T) {
+	feed := &AvitoFeed{Ad: []Ad{
+		{ID: "parking", Category: string(avitoCategoryGarage), ObjectType: avitoObjectTypeParking, ObjectSubtype: "Многоуровневый паркинг", Secured: "Да"},
+		{ID: "garage", Category: string(avitoCategoryGarage), ObjectType: "Гараж", ObjectSubtype: "Кирпичный", Secured: "Да"},
+		{ID: "storage", Category: string(avitoCategoryCommercial), ObjectType: avitoObjectTypeStorage, Square: 4, NewDevelopmentId: "1001"},
+		{ID: "land", Category: string(avitoCategoryLand), ObjectType: "Поселений (ИЖС)", LandArea: 10},
+		{ID: "house", Category: string(avitoCategoryHouse), ObjectType: "Дом", Square: 120, LandArea: 6, Floors: 2},
+		{ID: "secondary", Category: string(avitoCategoryFlat), MarketType: string(avitoMarketTypeSecondary), HouseType: "Панельный",
+			Floor: 3, Floors: 9, Rooms: "2", Square: 50, LivingSpace: 30},
+		{ID: "new", Category: string(avitoCategoryFlat), MarketType: string(avitoMarketTypeNew), HouseType: "Монолитный",
+			Floor: 3, Floors: 9, Rooms: "Студия", Square: 25},
+		{ID: "rent", Category: string(avitoCategoryRoom), OperationType: string(avitoOperationTypeRent), HouseType: "Панельный",
+			Floor: 3, Floors: 9, Rooms: "1", Square: 15},
+	}}
+
+	want := map[string][]string{
+		"garage": {"Ad.Square"},
+		"house":  {"Ad.WallsType"},
+		"new":    {"Ad.Status", "Ad.NewDevelopmentId", "Ad.PropertyRights", "Ad.Decoration"},
+		"rent":   {"Ad.LeaseType"},
+	}
+	if got := avitoCategoryFields(feed); !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %q, want %q", got, want)
+	}
+}
+
+func TestAvitoCategoryType(t *testing.T) {
+	tests := []struct {
+		ad   Ad
+		want avitoCategory
+	}{
+		{Ad{Category: " Квартиры "}, avitoCategoryFlat},
+		{Ad{Category: string(avitoCategoryGarage), ObjectType: "кладовая"}, avitoCategoryStorage},
+		{Ad{Category: string(avitoCategoryCommercial), ObjectType: avitoObjectTypeStorage}, avitoCategoryStorage},
+		{Ad{Category: string(avitoCategoryCommercial), ObjectType: "Офисное помещение"}, avitoCategoryCommercial},
+		{Ad{Category: string(avitoCategoryLand), ObjectType: avitoObjectTypeStorage}, avitoCategoryLand},
+	}
+	for _, tt := range tests {
+		if got := tt.ad.CategoryType(); got != string(tt.want) {
+			t.Errorf("CategoryType(%q, %q) = %q, want %q", tt.ad.Category, tt.ad.ObjectType, got, tt.want)
+		}
+	}
+}
+
+func TestAvitoStorageLots(t *testing.T) {
+	feed := &AvitoFeed{}
+	if results := feed.FromLots([]Lot{{ID: "storage-1", Category: LotCategoryStorage, Deal: DealTypeSale, Area: 4}}); len(results) != 0 {
+		t.Fatalf("FromLots() = %q", results)
+	}
+	ad := feed.Ad[0]
+	if ad.Category != string(avitoCategoryGarage) || ad.ObjectType != avitoObjectTypeStorage {
+		t.Errorf("ad Category = %q, ObjectType = %q", ad.Category, ad.ObjectType)
+	}
+	if issues := DefaultRules.Only("avito/enums").CheckIssues(feed, CheckOptions{}); len(issues) != 0 {
+		t.Errorf("enum issues = %+v", issues)
+	}
+	if avitoCategoryEnum.Contains(string(avitoCategoryStorage)) {
+		t.Error("storage pseudo-category is accepted")
+	}
+
+	lots, results := feed.Lots()
+	if len(results) != 0 || len(lots) != 1 || lots[0].Category != LotCategoryStorage {
+		t.Errorf("Lots() = %+v, %q", lots, results)
+	}
+}
@@ -0,0 +1,165 @@
+package price_placements_feeds
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Baseline lists accepted issues that are suppressed in reports. It is usually kept in a JSON file
+// next to the monitor config and generated with NewBaseline from the current results.
+type Baseline struct {
+	Entries []BaselineEntry `json:"entries"`
+}
+
+// BaselineEntry suppresses the issues matching Rule, Field and Lot. Rule is a rule name like "avito/required"
+// and Lot a lot identifier. Rule, Field and Lot are patterns where "*" matches any run of characters
+// and "?" one character; an empty Field matches any field.
+type BaselineEntry struct {
+	Rule   string `json:"rule"`
+	Field  string `json:"field,omitempty"`
+	Lot    string `json:"lot"`
+	Reason string `json:"reason"`
+	// Expires is a date like "2024-12-31" after which the entry no longer suppresses issues.
+	Expires string `json:"expires,omitempty"`
+}
+
+// SuppressedIssue is an issue suppressed by a baseline entry.
+type SuppressedIssue struct {
+	Issue
+	Reason  string `json:"reason"`
+	Expires string `json:"expires,omitempty"`
+}
+
+const baselineDateLayout = "2006-01-02"
+
+// LoadBaseline reads a baseline from a JSON file and validates its entries.
+func LoadBaseline(filename string) (*Baseline, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, err
+	}
+	if err := baseline.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &baseline, nil
+}
+
+// Save writes the baseline to a JSON file.
+func (b *Baseline) Save(filename string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+func (b *Baseline) validate() error {
+	for idx, entry := range b.Entries {
+		if entry.Rule == "" || entry.Lot == "" {
+			return fmt.Errorf("baseline entry %d: rule and lot are required", idx)
+		}
+		if entry.Expires != "" {
+			if _, err := time.Parse(baselineDateLayout, entry.Expires); err != nil {
+				return fmt.Errorf("baseline entry %d: expires must look like 2024-12-31: %s", idx, entry.Expires)
+			}
+		}
+	}
+	return nil
+}
+
+// NewBaseline makes a baseline accepting the given issues, one entry per rule, field and lot.
+// Feed issues and issues of lots without an identifier are left out, they can't be suppressed:
+// the position of a lot changes whenever the feed does.
+func NewBaseline(issues []Issue, reason string, expires string) *Baseline {
+	baseline := &Baseline{}
+	seen := make(map[BaselineEntry]bool)
+	for _, issue := range issues {
+		if issue.LotID == "" {
+			continue
+		}
+		entry := BaselineEntry{Rule: issue.Rule, Field: issue.Field, Lot: issue.LotID, Reason: reason, Expires: expires}
+		if seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		baseline.Entries = append(baseline.Entries, entry)
+	}
+	sort.SliceStable(baseline.Entries, func(i, j int) bool {
+		a, b := baseline.Entries[i], baseline.Entries[j]
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		return a.Lot < b.Lot
+	})
+	return baseline
+}
+
+// Merge adds the entries of other that are not in b yet, keeping the reasons already in b.
+func (b *Baseline) Merge(other *Baseline) {
+	known := make(map[string]bool)
+	for _, entry := range b.Entries {
+		known[entry.Rule+"\x00"+entry.Field+"\x00"+entry.Lot] = true
+	}
+	for _, entry := range other.Entries {
+		if !known[entry.Rule+"\x00"+entry.Field+"\x00"+entry.Lot] {
+			b.Entries = append(b.Entries, entry)
+		}
+	}
+}
+
+// Apply splits issues into new ones and the ones suppressed by a baseline entry not expired at now.
+func (b *Baseline) Apply(issues []Issue, now time.Time) (fresh []Issue, suppressed []SuppressedIssue) {
+	for _, issue := range issues {
+		if entry, ok := b.match(issue, now); ok {
+			suppressed = append(suppressed, SuppressedIssue{Issue: issue, Reason: entry.Reason, Expires: entry.Expires})
+			continue
+		}
+		fresh = append(fresh, issue)
+	}
+	return fresh, suppressed
+}
+
+func (b *Baseline) match(issue Issue, now time.Time) (entry BaselineEntry, ok bool) {
+	if issue.LotID == "" {
+		return entry, false
+	}
+	for _, entry := range b.Entries {
+		if entry.Expires != "" {
+			expires, err := time.ParseInLocation(baselineDateLayout, entry.Expires, now.Location())
+			if err != nil || !now.Before(expires.AddDate(0, 0, 1)) {
+				continue
+			}
+		}
+		if !matchPattern(entry.Rule, issue.Rule) || !matchPattern(entry.Lot, issue.LotID) {
+			continue
+		}
+		if entry.Field != "" && !matchPattern(entry.Field, issue.Field) {
+			continue
+		}
+		return entry, true
+	}
+	return entry, false
+}
+
+func matchPattern(pattern string, value string) bool {
+	if !strings.ContainsAny(pattern, "*?") {
+		return pattern == value
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	ok, _ := regexp.MatchString("^"+expr+"$", value)
+	return ok
+}
@@ -0,0 +1,88 @@
+package price_placements_feeds
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewBaselineUsesRulesAndLotIDs(t *testing.T) {
+	feed := testAvitoFeed(12)
+	feed.Ad[2].Price = 0
+	feed.Ad[4].ID = ""
+	feed.Ad[4].Price = 0
+	issues := DefaultRules.Only("avito/ids", "avito/required").CheckIssues(feed, CheckOptions{})
+
+	baseline := NewBaseline(issues, "accepted", "")
+	want := []BaselineEntry{{Rule: "avito/required", Field: "Ad.Price", Lot: "ad-2", Reason: "accepted"}}
+	if !reflect.DeepEqual(baseline.Entries, want) {
+		t.Errorf("entries = %+v, want %+v", baseline.Entries, want)
+	}
+
+	fresh, suppressed := baseline.Apply(issues, time.Now())
+	if len(suppressed) != 1 || suppressed[0].LotID != "ad-2" {
+		t.Errorf("suppressed = %+v", suppressed)
+	}
+	// The lot without an identifier is not matched by its position.
+	for _, issue := range fresh {
+		if issue.LotID != "" {
+			t.Errorf("issue %+v is not suppressed", issue)
+		}
+	}
+	if len(fresh) != 2 {
+		t.Errorf("fresh = %+v", fresh)
+	}
+}
+
+func TestBaselineExpiresAndPatterns(t *testing.T) {
+	baseline := &Baseline{Entries: []BaselineEntry{
+		{Rule: "avito/*", Lot: "ad-?", Reason: "old lots", Expires: "2024-06-30"},
+	}}
+	issue := Issue{Rule: "avito/required", Field: "Ad.Price", LotID: "ad-1"}
+
+	if _, suppressed := baseline.Apply([]Issue{issue}, time.Date(2024, 6, 30, 23, 0, 0, 0, time.UTC)); len(suppressed) != 1 {
+		t.Error("issue is not suppressed on the expiry date")
+	}
+	if fresh, _ := baseline.Apply([]Issue{issue}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)); len(fresh) != 1 {
+		t.Error("expired entry suppresses the issue")
+	}
+	issue.LotID = "ad-10"
+	if fresh, _ := baseline.Apply([]Issue{issue}, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)); len(fresh) != 1 {
+		t.Error("lot pattern ad-? matches ad-10")
+	}
+}
+
+func TestMonitorCountsSuppressedIssues(t *testing.T) {
+	feed := testAvitoFeed(12)
+	feed.Ad[2].Price = 0
+	feed.Ad[5].Price = 0
+	data, err := xml.Marshal(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	monitor := NewMonitor(MonitorConfig{}, nil)
+	monitor.Baseline = &Baseline{Entries: []BaselineEntry{{Rule: "avito/required", Field: "Ad.Price", Lot: "ad-2", Reason: "accepted"}}}
+	result, err := monitor.Check("test", ProjectFeed{Platform: PlatformAvito, URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+	if result.Suppressed != 1 {
+		t.Errorf("Suppressed = %d, want 1", result.Suppressed)
+	}
+	for _, issue := range result.Issues {
+		if issue.LotID == "ad-2" {
+			t.Errorf("suppressed issue is reported: %+v", issue)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package price_placements_feeds
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// BatchOptions configure CheckFeeds. Zero fields take their defaults.
+type BatchOptions struct {
+	// Workers is the number of feeds fetched and checked at the same time. Defaults to 4.
+	Workers int
+	// PerHost is the number of feeds fetched from the same host at the same time. Defaults to 1.
+	PerHost int
+	// Fetcher downloads the feeds. Defaults to DefaultFetcher; the credentials and proxy of a feed are added to a copy of it.
+	Fetcher *Fetcher
+	// Rules are the rules the feeds are checked with. Defaults to DefaultRules.
+	Rules *RuleSet
+	// Check are the options of the check, e.g. the date the feeds are checked as of.
+	Check CheckOptions
+}
+
+// BatchResult is the outcome of fetching and checking one feed of a batch.
+type BatchResult struct {
+	Source ProjectFeed
+	// Feed is the parsed feed. It is nil when the feed couldn't be fetched.
+	Feed     Feed
+	Fetch    FetchResult
+	Issues   []Issue
+	Err      error
+	Duration time.Duration
+}
+
+// CheckFeeds fetches, parses and checks the feeds concurrently. The results are in the order of feeds.
+// Feeds not checked when ctx is done get the context error.
+func CheckFeeds(ctx context.Context, feeds []ProjectFeed, options BatchOptions) []BatchResult {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = 4
+	}
+	perHost := options.PerHost
+	if perHost <= 0 {
+		perHost = 1
+	}
+
+	results := make([]BatchResult, len(feeds))
+	hosts := make([]string, len(feeds))
+	var pending []int
+	for idx, feed := range feeds {
+		results[idx].Source = feed
+		u, err := url.Parse(feed.URL)
+		if err != nil {
+			results[idx].Err = &FetchError{URL: redactURL(feed.URL), Err: err}
+			continue
+		}
+		hosts[idx] = u.Host
+		pending = append(pending, idx)
+	}
+
+	var (
+		mu         sync.Mutex
+		ready      = sync.NewCond(&mu)
+		hostActive = make(map[string]int)
+	)
+	// next takes the first pending feed whose host is below the limit, waiting for one when all hosts are busy.
+	next := func() (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		for len(pending) > 0 && ctx.Err() == nil {
+			for i, idx := range pending {
+				if hostActive[hosts[idx]] < perHost {
+					pending = append(pending[:i], pending[i+1:]...)
+					hostActive[hosts[idx]]++
+					return idx, true
+				}
+			}
+			ready.Wait()
+		}
+		return 0, false
+	}
+	done := func(idx int) {
+		mu.Lock()
+		hostActive[hosts[idx]]--
+		mu.Unlock()
+		ready.Broadcast()
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// Taking the lock makes sure no worker is between checking ctx and waiting.
+			mu.Lock()
+			mu.Unlock()
+			ready.Broadcast()
+		case <-stop:
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				idx, ok := next()
+				if !ok {
+					return
+				}
+				results[idx] = checkBatchFeed(ctx, feeds[idx], options)
+				done(idx)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, idx := range pending {
+		results[idx].Err = ctx.Err()
+	}
+	return results
+}
+
+func checkBatchFeed(ctx context.Context, source ProjectFeed, options BatchOptions) (result BatchResult) {
+	start := time.Now()
+	result.Source = source
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	feed, err := NewFeed(source.Platform)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Fetch, result.Err = options.Fetcher.forFeed(source).FetchFeedContext(ctx, feed, source.urls()...)
+	if result.Err != nil {
+		return result
+	}
+
+	result.Feed = feed
+	rules := options.Rules
+	if rules == nil {
+		rules = DefaultRules
+	}
+	result.Issues = rules.CheckIssues(feed, options.Check)
+	return result
+}
@@ -0,0 +1,112 @@
+package price_placements_feeds
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testFeedServer serves Avito feeds of 12 ads and counts its concurrent requests.
+type testFeedServer struct {
+	*httptest.Server
+	active, maxActive int32
+}
+
+func newTestFeedServer(t *testing.T, delay time.Duration) *testFeedServer {
+	t.Helper()
+	data, err := xml.Marshal(testAvitoFeed(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &testFeedServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		active := atomic.AddInt32(&server.active, 1)
+		defer atomic.AddInt32(&server.active, -1)
+		for {
+			max := atomic.LoadInt32(&server.maxActive)
+			if active <= max || atomic.CompareAndSwapInt32(&server.maxActive, max, active) {
+				break
+			}
+		}
+		if r.URL.Path == "/broken.xml" {
+			w.Write([]byte("<Ads><Ad>"))
+			return
+		}
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Last-Modified", testNow.Format(http.TimeFormat))
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckFeeds(t *testing.T) {
+	first, second := newTestFeedServer(t, 20*time.Millisecond), newTestFeedServer(t, 20*time.Millisecond)
+	feeds := []ProjectFeed{
+		{Platform: PlatformAvito, URL: first.URL + "/1.xml"},
+		{Platform: PlatformAvito, URL: second.URL + "/1.xml"},
+		{Platform: PlatformAvito, URL: first.URL + "/broken.xml"},
+		{Platform: "ozon", URL: second.URL + "/2.xml"},
+		{Platform: PlatformAvito, URL: "http://[::1"},
+		{Platform: PlatformAvito, URL: first.URL + "/2.xml"},
+		{Platform: PlatformAvito, URL: second.URL + "/3.xml"},
+	}
+
+	results := CheckFeeds(context.Background(), feeds, BatchOptions{Workers: 4, PerHost: 2, Check: CheckOptions{Now: testClock}})
+	if len(results) != len(feeds) {
+		t.Fatalf("results = %d, want %d", len(results), len(feeds))
+	}
+	for idx, result := range results {
+		if result.Source.URL != feeds[idx].URL {
+			t.Errorf("result %d is for %s, want %s", idx, result.Source.URL, feeds[idx].URL)
+		}
+		failed := idx == 2 || idx == 3 || idx == 4
+		if (result.Err != nil) != failed {
+			t.Errorf("result %d: error %v", idx, result.Err)
+		}
+		if !failed && (result.Feed == nil || len(result.Issues) != 0 || result.Fetch.Attempts != 1) {
+			t.Errorf("result %d = %+v", idx, result)
+		}
+	}
+	if kind := ErrorKindOf(results[2].Err); kind != ErrorKindDecode {
+		t.Errorf("broken feed error kind = %s", kind)
+	}
+
+	for _, server := range []*testFeedServer{first, second} {
+		if max := atomic.LoadInt32(&server.maxActive); max > 2 {
+			t.Errorf("concurrent requests to %s = %d, want at most 2", server.URL, max)
+		}
+	}
+}
+
+func TestCheckFeedsCancelled(t *testing.T) {
+	server := newTestFeedServer(t, time.Second)
+	var feeds []ProjectFeed
+	for i := 0; i < 4; i++ {
+		feeds = append(feeds, ProjectFeed{Platform: PlatformAvito, URL: server.URL + "/feed.xml"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results := CheckFeeds(ctx, feeds, BatchOptions{PerHost: 1, Fetcher: &Fetcher{Retry: RetryPolicy{MaxAttempts: 1}}})
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("CheckFeeds() returned after %s", elapsed)
+	}
+	for idx, result := range results {
+		if result.Err == nil || result.Feed != nil {
+			t.Errorf("result %d = %+v", idx, result)
+		}
+	}
+	if max := atomic.LoadInt32(&server.maxActive); max != 1 {
+		t.Errorf("concurrent requests = %d, want 1", max)
+	}
+}
@@ -0,0 +1,157 @@
+package price_placements_feeds
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BuildingCoordinateTolerance is the distance in meters a lot may be from the other lots of its building.
+var BuildingCoordinateTolerance = 300.0
+
+// buildingLot is a lot with the attributes that must be the same for the whole building.
+type buildingLot struct {
+	id       string
+	position int
+	// values maps field paths to values. Empty values are not compared.
+	values map[string]string
+	// latitude and longitude are compared by distance rather than by value.
+	latitude  string
+	longitude string
+}
+
+type buildingGroup struct {
+	name string
+	lots []buildingLot
+}
+
+// buildingGroups groups lots by building, keeping the order of the first lot of each building.
+type buildingGroups struct {
+	groups []*buildingGroup
+	index  map[string]*buildingGroup
+}
+
+func (g *buildingGroups) add(key string, name string, lot buildingLot) {
+	if g.index == nil {
+		g.index = make(map[string]*buildingGroup)
+	}
+	group, ok := g.index[key]
+	if !ok {
+		group = &buildingGroup{name: name}
+		g.index[key] = group
+		g.groups = append(g.groups, group)
+	}
+	group.lots = append(group.lots, lot)
+}
+
+// check reports, building by building and field by field, the lots whose value differs from
+// the one most lots of the building have. When no value is the most common, the building is
+// reported as a whole: there is no way to tell the wrong lots.
+func (g *buildingGroups) check(fields []string, results *Results) {
+	for _, group := range g.groups {
+		for _, field := range fields {
+			counts := make(map[string]int)
+			var values []string
+			for _, lot := range group.lots {
+				value := lot.values[field]
+				if value == "" {
+					continue
+				}
+				if counts[value] == 0 {
+					values = append(values, value)
+				}
+				counts[value]++
+			}
+			if len(values) < 2 {
+				continue
+			}
+			sort.SliceStable(values, func(i, j int) bool { return counts[values[i]] > counts[values[j]] })
+			if counts[values[0]] == counts[values[1]] {
+				results.AddLot(-1, field, fmt.Sprintf("field %s has different values for building '%s' and none is used by most lots: '%s'", field, group.name, strings.Join(values, "', '")))
+				continue
+			}
+			for _, lot := range group.lots {
+				if value := lot.values[field]; value != "" && value != values[0] {
+					results.AddLot(lot.position, field, fmt.Sprintf("field %s is '%s', but '%s' for most lots of building '%s'. InternalID: %s", field, value, values[0], group.name, lot.id))
+				}
+			}
+		}
+	}
+}
+
+// checkCoordinates reports the lots farther than BuildingCoordinateTolerance from the median point of their building.
+// Buildings with less than three lots with coordinates are skipped, there is no majority to compare with.
+func (g *buildingGroups) checkCoordinates(field string, results *Results) {
+	type point struct {
+		lot                 buildingLot
+		latitude, longitude float64
+	}
+	for _, group := range g.groups {
+		var points []point
+		for _, lot := range group.lots {
+			latitude, err := strconv.ParseFloat(strings.TrimSpace(lot.latitude), 64)
+			if err != nil || latitude == 0 {
+				continue
+			}
+			longitude, err := strconv.ParseFloat(strings.TrimSpace(lot.longitude), 64)
+			if err != nil || longitude == 0 {
+				continue
+			}
+			points = append(points, point{lot, latitude, longitude})
+		}
+		if len(points) < 3 {
+			continue
+		}
+
+		latitudes, longitudes := make([]float64, len(points)), make([]float64, len(points))
+		for idx, p := range points {
+			latitudes[idx], longitudes[idx] = p.latitude, p.longitude
+		}
+		latitude, longitude := median(latitudes), median(longitudes)
+		for _, p := range points {
+			if distance := earthDistance(p.latitude, p.longitude, latitude, longitude); distance > BuildingCoordinateTolerance {
+				results.AddLot(p.lot.position, field, fmt.Sprintf("field %s is '%s, %s', %.0f m from most lots of building '%s'. InternalID: %s",
+					field, p.lot.latitude, p.lot.longitude, distance, group.name, p.lot.id))
+			}
+		}
+	}
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// earthDistance is the great-circle distance in meters between two points given in degrees.
+func earthDistance(latitude1, longitude1, latitude2, longitude2 float64) float64 {
+	const earthRadius = 6371000
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	dLatitude := toRadians(latitude2 - latitude1)
+	dLongitude := toRadians(longitude2 - longitude1)
+	a := math.Sin(dLatitude/2)*math.Sin(dLatitude/2) +
+		math.Cos(toRadians(latitude1))*math.Cos(toRadians(latitude2))*math.Sin(dLongitude/2)*math.Sin(dLongitude/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// formatBuildingInt formats a number for comparison, leaving zero out as a missing value.
+func formatBuildingInt(value int64) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+// formatBuildingFloat formats a coordinate for comparison, leaving zero out as a missing value.
+func formatBuildingFloat(value float32) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(value), 'f', -1, 32)
+}
@@ -0,0 +1,55 @@
+package price_placements_feeds
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildingConsistencyReportsTiesForTheBuilding(t *testing.T) {
+	feed := testCianFeed(4)
+	feed.Object[2].Building.FloorsCount = 12
+	feed.Object[3].Building.FloorsCount = 12
+
+	issues := DefaultRules.Only("cian/building-consistency").CheckIssues(feed, CheckOptions{})
+	if len(issues) != 1 {
+		t.Fatalf("issues = %+v", issues)
+	}
+	issue := issues[0]
+	want := "field object.Building.FloorsCount has different values for building 'Корпус 1' and none is used by most lots: '9', '12'"
+	if !issue.IsFeedIssue() || issue.Field != "object.Building.FloorsCount" || issue.Message != want {
+		t.Errorf("issue = %+v, want a building issue %q", issue, want)
+	}
+}
+
+func TestCianBuildingNameKeepsTheHouseName(t *testing.T) {
+	feed := testCianFeed(4)
+	for i := range feed.Object {
+		feed.Object[i].JKSchema.House.ID = 1234
+	}
+	feed.Object[0].Building.FloorsCount = 12
+
+	results := DefaultRules.Only("cian/building-consistency").Check(feed)
+	want := []string{"field object.Building.FloorsCount is '12', but '9' for most lots of building 'Корпус 1 (1234)'. InternalID: obj-0"}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Check() = %q, want %q", results, want)
+	}
+
+	key, name, ok := cianBuilding(Object{})
+	if ok || key != "" || name != "" {
+		t.Errorf("cianBuilding() of an object without a house = %q, %q, %v", key, name, ok)
+	}
+}
+
+func TestAvitoBuildingCoordinates(t *testing.T) {
+	feed := testAvitoFeed(12)
+	for i := range feed.Ad {
+		feed.Ad[i].NewDevelopmentId = "1001"
+		feed.Ad[i].Latitude, feed.Ad[i].Longitude = "55.7500", "37.6100"
+	}
+	feed.Ad[5].Latitude = "55.7600"
+
+	issues := DefaultRules.Only("avito/building-consistency").CheckIssues(feed, CheckOptions{})
+	if len(issues) != 1 || issues[0].Field != "Ad.Coordinates" || issues[0].LotID != "ad-5" {
+		t.Errorf("issues = %+v", issues)
+	}
+}
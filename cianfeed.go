@@ -4,100 +4,181 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
-	"log"
 	"strconv"
 	"strings"
 	"time"
 )
 
 type CianFeed struct {
-	LastModified time.Time
-	FeedVersion  string   `xml:"feed_version"`
-	Object       []Object `xml:"object"`
+	LastModified time.Time `xml:"-"`
+	XMLName      xml.Name
+	FeedVersion  string   `xml:"feed_version,omitempty"`
+	Object       []Object `xml:"object,omitempty"`
+
+	Unknown     []UnknownElement `xml:",any"`
+	UnknownAttr []xml.Attr       `xml:",any,attr"`
+	Strict      bool             `xml:"-"` // Check also reports unknown elements and attributes
 }
 
 type Object struct {
-	ExternalId  string `xml:"ExternalId"`
-	Description string `xml:"Description"`
-	Address     string `xml:"Address"`
+	ExternalId  string `xml:"ExternalId,omitempty"`
+	Description string `xml:"Description,omitempty"`
+	Address     string `xml:"Address,omitempty"`
 	Coordinates struct {
-		Lat float32 `xml:"Lat"`
-		Lng float32 `xml:"Lng"`
-	} `xml:"Coordinates"`
-	CadastralNumber string `xml:"CadastralNumber"`
+		Lat float32 `xml:"Lat,omitempty"`
+		Lng float32 `xml:"Lng,omitempty"`
+	} `xml:"Coordinates,omitempty"`
+	CadastralNumber string `xml:"CadastralNumber,omitempty"`
 	Phones          struct {
-		PhoneSchema struct {
-			CountryCode string `xml:"CountryCode"`
-			Number      string `xml:"Number"`
-		} `xml:"PhoneSchema"`
-	} `xml:"Phones"`
-	LayoutPhoto struct {
-		IsDefault bool   `xml:"IsDefault"`
-		FullUrl   string `xml:"FullUrl"`
-	} `xml:"LayoutPhoto"`
-	Photos struct {
-		PhotoSchema []PhotoSchema `xml:"PhotoSchema"`
-	} `xml:"Photos"`
-	Category              string  `xml:"Category"`
-	RoomType              string  `xml:"RoomType"`
-	FlatRoomsCount        int64   `xml:"FlatRoomsCount"`
-	TotalArea             float32 `xml:"TotalArea"`
-	LivingArea            float32 `xml:"LivingArea"`
-	KitchenArea           float32 `xml:"KitchenArea"`
-	ProjectDeclarationUrl string  `xml:"ProjectDeclarationUrl"`
-	FloorNumber           int64   `xml:"FloorNumber"`
-	CombinedWcsCount      int64   `xml:"CombinedWcsCount"`
+		PhoneSchema []PhoneSchema `xml:"PhoneSchema,omitempty"`
+	} `xml:"Phones,omitempty"`
+	LayoutPhoto []PhotoSchema `xml:"LayoutPhoto,omitempty"`
+	Photos      struct {
+		PhotoSchema []PhotoSchema `xml:"PhotoSchema,omitempty"`
+	} `xml:"Photos,omitempty"`
+	Category              string  `xml:"Category,omitempty"`
+	RoomType              string  `xml:"RoomType,omitempty"`
+	FlatRoomsCount        int64   `xml:"FlatRoomsCount,omitempty"`
+	TotalArea             float32 `xml:"TotalArea,omitempty"`
+	LivingArea            float32 `xml:"LivingArea,omitempty"`
+	KitchenArea           float32 `xml:"KitchenArea,omitempty"`
+	ProjectDeclarationUrl string  `xml:"ProjectDeclarationUrl,omitempty"`
+	FloorNumber           int64   `xml:"FloorNumber,omitempty"`
+	CombinedWcsCount      int64   `xml:"CombinedWcsCount,omitempty"`
+	SeparateWcsCount      int64   `xml:"SeparateWcsCount,omitempty"`
+	BalconiesCount        int64   `xml:"BalconiesCount,omitempty"`
+	LoggiasCount          int64   `xml:"LoggiasCount,omitempty"`
 	Building              struct {
-		FloorsCount         int64  `xml:"FloorsCount"`
-		MaterialType        string `xml:"MaterialType"`
-		PassengerLiftsCount int64  `xml:"PassengerLiftsCount"`
-		CargoLiftsCount     int64  `xml:"CargoLiftsCount"`
+		FloorsCount         int64  `xml:"FloorsCount,omitempty"`
+		MaterialType        string `xml:"MaterialType,omitempty"`
+		PassengerLiftsCount int64  `xml:"PassengerLiftsCount,omitempty"`
+		CargoLiftsCount     int64  `xml:"CargoLiftsCount,omitempty"`
 		Parking             struct {
-			Type string `xml:"Type"`
-		} `xml:"Parking"`
+			Type string `xml:"Type,omitempty"`
+		} `xml:"Parking,omitempty"`
 		Deadline struct {
-			Quarter    string `xml:"Quarter"`
-			Year       int64  `xml:"Year"`
-			IsComplete bool   `xml:"IsComplete"`
-		} `xml:"Deadline"`
-	} `xml:"Building"`
+			Quarter    string `xml:"Quarter,omitempty"`
+			Year       int64  `xml:"Year,omitempty"`
+			IsComplete bool   `xml:"IsComplete,omitempty"`
+		} `xml:"Deadline,omitempty"`
+	} `xml:"Building,omitempty"`
 	BargainTerms struct {
-		Price           CustomFloat64 `xml:"Price"`
-		Currency        string        `xml:"Currency"`
-		MortgageAllowed bool          `xml:"MortgageAllowed"`
-		SaleType        string        `xml:"SaleType"`
-	} `xml:"BargainTerms"`
+		Price           CustomFloat64 `xml:"Price,omitempty"`
+		Currency        string        `xml:"Currency,omitempty"`
+		MortgageAllowed bool          `xml:"MortgageAllowed,omitempty"`
+		SaleType        string        `xml:"SaleType,omitempty"`
+	} `xml:"BargainTerms,omitempty"`
 	JKSchema struct {
-		ID    int32  `xml:"Id"`
-		Name  string `xml:"Name"`
+		ID    int32  `xml:"Id,omitempty"`
+		Name  string `xml:"Name,omitempty"`
 		House struct {
-			ID   int32  `xml:"Id"`
-			Name string `xml:"Name"`
+			ID   int32  `xml:"Id,omitempty"`
+			Name string `xml:"Name,omitempty"`
 			Flat struct {
-				FlatNumber    string `xml:"FlatNumber"`
-				SectionNumber string `xml:"SectionNumber"`
-				FlatType      string `xml:"FlatType"`
-			} `xml:"Flat"`
-		} `xml:"House"`
-	} `xml:"JKSchema"`
-	Decoration      string  `xml:"Decoration"`
-	WindowsViewType string  `xml:"WindowsViewType"`
-	CeilingHeight   float32 `xml:"CeilingHeight"`
+				FlatNumber    string `xml:"FlatNumber,omitempty"`
+				SectionNumber string `xml:"SectionNumber,omitempty"`
+				FlatType      string `xml:"FlatType,omitempty"`
+			} `xml:"Flat,omitempty"`
+		} `xml:"House,omitempty"`
+	} `xml:"JKSchema,omitempty"`
+	Decoration      string  `xml:"Decoration,omitempty"`
+	WindowsViewType string  `xml:"WindowsViewType,omitempty"`
+	CeilingHeight   float32 `xml:"CeilingHeight,omitempty"`
 	Undergrounds    struct {
-		UndergroundInfoSchema []struct {
-			TransportType string `xml:"TransportType"`
-			Time          int64  `xml:"Time"`
-			ID            int64  `xml:"Id"`
-		} `xml:"UndergroundInfoSchema"`
-	} `xml:"Undergrounds"`
-	IsApartments bool `xml:"isApartments"`
+		UndergroundInfoSchema []UndergroundInfoSchema `xml:"UndergroundInfoSchema,omitempty"`
+	} `xml:"Undergrounds,omitempty"`
+	IsApartments bool `xml:"isApartments,omitempty"`
+
+	Unknown     []UnknownElement `xml:",any"`
+	UnknownAttr []xml.Attr       `xml:",any,attr"`
+}
+
+type PhoneSchema struct {
+	CountryCode string `xml:"CountryCode,omitempty"`
+	Number      string `xml:"Number,omitempty"`
 }
 
 type PhotoSchema struct {
-	FullUrl   string `xml:"FullUrl"`
-	IsDefault bool   `xml:"IsDefault"`
+	FullUrl   string `xml:"FullUrl,omitempty"`
+	IsDefault bool   `xml:"IsDefault,omitempty"`
+}
+
+type UndergroundInfoSchema struct {
+	TransportType string `xml:"TransportType,omitempty"`
+	Time          int64  `xml:"Time,omitempty"`
+	ID            int64  `xml:"Id,omitempty"`
 }
 
+// cianDecoration is the value of object.Decoration.
+type cianDecoration string
+
+const (
+	cianDecorationWithout cianDecoration = "without"
+	cianDecorationRough   cianDecoration = "rough"
+	cianDecorationPreFine cianDecoration = "preFine"
+	cianDecorationFine    cianDecoration = "fine"
+)
+
+// cianFlatType is the value of object.JKSchema.House.Flat.FlatType.
+type cianFlatType string
+
+const (
+	cianFlatTypeRooms    cianFlatType = "rooms"
+	cianFlatTypeOpenPlan cianFlatType = "openPlan"
+	cianFlatTypeStudio   cianFlatType = "studio"
+)
+
+// cianRoomType is the value of object.RoomType.
+type cianRoomType string
+
+const (
+	cianRoomTypeSeparate cianRoomType = "separate"
+	cianRoomTypeCombined cianRoomType = "combined"
+	cianRoomTypeBoth     cianRoomType = "both"
+)
+
+// cianWindowsView is the value of object.WindowsViewType.
+type cianWindowsView string
+
+const (
+	cianWindowsViewStreet        cianWindowsView = "street"
+	cianWindowsViewYard          cianWindowsView = "yard"
+	cianWindowsViewYardAndStreet cianWindowsView = "yardAndStreet"
+)
+
+// cianTransport is the value of UndergroundInfoSchema.TransportType.
+type cianTransport string
+
+const (
+	cianTransportWalk      cianTransport = "walk"
+	cianTransportTransport cianTransport = "transport"
+)
+
+// cianQuarter is the value of object.Building.Deadline.Quarter.
+type cianQuarter string
+
+const (
+	cianQuarterFirst  cianQuarter = "first"
+	cianQuarterSecond cianQuarter = "second"
+	cianQuarterThird  cianQuarter = "third"
+	cianQuarterFourth cianQuarter = "fourth"
+)
+
+var (
+	cianCategoryEnum = Enum{Values: append(cianCategoryValues(),
+		"dailyFlatRent", "dailyRoomRent", "dailyHouseRent", "dailyBedRent", "bedRent",
+		"flatShareSale", "houseShareSale", "houseShareRent", "cottageRent", "townhouseRent",
+		"commercialLandSale", "commercialLandRent", "buildingSale", "buildingRent", "businessSale",
+		"industrySale", "industryRent", "shoppingAreaRent", "warehouseRent",
+	)}
+	cianDecorationEnum       = Enum{Values: enumValues(cianDecorations.values()...)}
+	cianFlatTypes            = Enum{Values: enumValues(cianFlatTypeRooms, cianFlatTypeOpenPlan, cianFlatTypeStudio)}
+	cianRoomTypes            = Enum{Values: enumValues(cianRoomTypeSeparate, cianRoomTypeCombined, cianRoomTypeBoth)}
+	cianWindowsViewTypes     = Enum{Values: enumValues(cianWindowsViewStreet, cianWindowsViewYard, cianWindowsViewYardAndStreet)}
+	cianUndergroundTransport = Enum{Values: enumValues(cianTransportWalk, cianTransportTransport)}
+	cianQuarterEnum          = Enum{Values: enumValues(cianQuarters.values()...)}
+)
+
 type CustomFloat64 struct {
 	Float64 float64
 }
@@ -116,88 +197,521 @@ func (cf *CustomFloat64) UnmarshalXML(d *xml.Decoder, start xml.StartElement) er
 	return nil
 }
 
+func (cf CustomFloat64) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if cf.Float64 == 0 {
+		return nil
+	}
+	return e.EncodeElement(strconv.FormatFloat(cf.Float64, 'f', -1, 64), start)
+}
+
+// Get downloads the feed with DefaultFetcher.
 func (f *CianFeed) Get(url string) (err error) {
-	resp, err := GetResponse(url)
-	if err != nil {
-		return err
+	_, err = DefaultFetcher.FetchFeed(f, url)
+	return err
+}
+
+// Marshal returns the feed as Cian XML.
+func (f *CianFeed) Marshal() ([]byte, error) {
+	return marshalFeed(f.withRoot(), map[string]bool{"Description": true})
+}
+
+// WriteTo writes the feed as Cian XML to w.
+func (f *CianFeed) WriteTo(w io.Writer) (n int64, err error) {
+	return writeFeed(w, f.withRoot(), map[string]bool{"Description": true})
+}
+
+// withRoot returns a copy of the feed named as the Cian root element. Any root element is accepted
+// when decoding.
+func (f *CianFeed) withRoot() *CianFeed {
+	feed := *f
+	feed.XMLName = xml.Name{Local: "feed"}
+	return &feed
+}
+
+// Check runs the enabled Cian rules of DefaultRules.
+func (f *CianFeed) Check() (results []string) {
+	return DefaultRules.Check(f)
+}
+
+// CheckWith is Check with options, e.g. checking the feed as of another date.
+func (f *CianFeed) CheckWith(options CheckOptions) (results []string) {
+	return DefaultRules.CheckWith(f, options)
+}
+
+var cianRules = []Rule{
+	gateRule(FeedRule("cian/feed-size", func(f *CianFeed, results *Results) {
+		if len(f.Object) < 2 {
+			results.Add("", emptyFeed)
+			return
+		}
+		if len(f.Object) <= 10 {
+			results.Add("", fmt.Sprintf("feed contains only %v items", len(f.Object)))
+		}
+	})),
+	freshnessRule[*CianFeed](PlatformCian),
+	lotRule("cian/ids", func(lot Object, run *checkRun, results *Results) {
+		if lot.ExternalId == "" {
+			results.Add("object.ExternalId", fmt.Sprintf("field ExternalId is empty. Position: %v", results.lot))
+		}
+	}),
+	LotRule("cian/required", func(lot Object, results *Results) {
+		id := lot.ExternalId
+		checkStringWithID(id, "object", "Address", lot.Address, results)
+		checkStringWithID(id, "object", "Category", lot.Category, results)
+		checkZeroWithID(id, "object", "FlatRoomsCount", int(lot.FlatRoomsCount), results)
+		checkZeroWithID(id, "object", "TotalArea", int(lot.TotalArea), results)
+		checkZeroWithID(id, "object", "FloorNumber", int(lot.FloorNumber), results)
+		checkZeroWithID(id, "object.Building", "FloorsCount", int(lot.Building.FloorsCount), results)
+		checkZeroWithID(id, "object.Building.Deadline", "Year", int(lot.Building.Deadline.Year), results)
+		checkStringWithID(id, "object.Building.Deadline", "Quarter", lot.Building.Deadline.Quarter, results)
+		checkZeroWithID(id, "object.BargainTerms.Price", "Price", int(lot.BargainTerms.Price.Float64), results)
+		checkZeroWithID(id, "object.JKSchema", "Id", int(lot.JKSchema.ID), results)
+		checkStringWithID(id, "object.JKSchema", "Name", lot.JKSchema.Name, results)
+		checkZeroWithID(id, "object.JKSchema.House", "Id", int(lot.JKSchema.House.ID), results)
+		checkStringWithID(id, "object.JKSchema.House", "Name", lot.JKSchema.House.Name, results)
+	}),
+	LotRule("cian/phones", checkCianPhones),
+	LotRule("cian/photos", func(lot Object, results *Results) {
+		checkCianLayoutPhotos(lot, results)
+
+		defaultPhotos := 0
+		for idx, photoSchema := range lot.Photos.PhotoSchema {
+			checkStringWithPos(idx, "object.Photos.PhotoSchema", "FullUrl", photoSchema.FullUrl, results)
+			if photoSchema.IsDefault {
+				defaultPhotos++
+			}
+		}
+		if len(lot.Photos.PhotoSchema) > 0 && defaultPhotos != 1 {
+			results.Add("object.Photos.PhotoSchema", fmt.Sprintf("field Photos.PhotoSchema contains '%v' default photos, expected 1. InternalID: %v", defaultPhotos, lot.ExternalId))
+		}
+		if len(lot.Photos.PhotoSchema) < 3 {
+			results.Add("object.Photos.PhotoSchema", fmt.Sprintf("field Photos.PhotoSchema contains '%v' items. InternalID: %v", len(lot.Photos.PhotoSchema), lot.ExternalId))
+		}
+	}),
+	LotRuleAt("cian/deadline", func(lot Object, now time.Time, results *Results) {
+		id, deadline := lot.ExternalId, lot.Building.Deadline
+		checkEnumWithID(id, "object.Building.Deadline", "Quarter", deadline.Quarter, cianQuarterEnum, results)
+		checkDeadlineYearWithID(id, "object.Building.Deadline", "Year", deadline.Year, now.Year(), results)
+
+		quarter, _ := cianQuarters.canonical(deadline.Quarter)
+		number, _ := strconv.ParseInt(quarter, 10, 64)
+		if passed, ok := deadlinePassed(deadline.Year, number, now.Year(), quarterOf(int(now.Month()))); ok && !deadline.IsComplete {
+			results.Add("object.Building.Deadline.IsComplete", fmt.Sprintf("field Building.Deadline is False for %v. InternalID: %v", passed, id))
+		}
+	}),
+	LotRule("cian/building", func(lot Object, results *Results) {
+		if lot.FloorNumber > lot.Building.FloorsCount {
+			results.Add("object.FloorNumber", fmt.Sprintf("field FloorNumber is greater than Building.FloorsCount. InternalID: %v", lot.ExternalId))
+		}
+	}),
+	FeedRule("cian/building-consistency", func(f *CianFeed, results *Results) {
+		var groups buildingGroups
+		for idx, lot := range f.Object {
+			key, name, ok := cianBuilding(lot)
+			if !ok {
+				continue
+			}
+			groups.add(key, name, buildingLot{
+				id:       lot.ExternalId,
+				position: idx,
+				values: map[string]string{
+					"object.Building.FloorsCount":  formatBuildingInt(lot.Building.FloorsCount),
+					"object.Building.MaterialType": lot.Building.MaterialType,
+					"object.Building.Deadline":     cianDeadline(lot),
+					"object.Address":               strings.Join(strings.Fields(lot.Address), " "),
+				},
+				latitude:  formatBuildingFloat(lot.Coordinates.Lat),
+				longitude: formatBuildingFloat(lot.Coordinates.Lng),
+			})
+		}
+		groups.check([]string{"object.Building.FloorsCount", "object.Building.MaterialType", "object.Building.Deadline", "object.Address"}, results)
+		groups.checkCoordinates("object.Coordinates", results)
+	}),
+	LotRule("cian/enums", func(lot Object, results *Results) {
+		id := lot.ExternalId
+		checkEnumWithID(id, "object", "Category", lot.Category, cianCategoryEnum, results)
+		checkEnumWithID(id, "object", "Decoration", lot.Decoration, cianDecorationEnum, results)
+		checkEnumWithID(id, "object.JKSchema.House.Flat", "FlatType", lot.JKSchema.House.Flat.FlatType, cianFlatTypes, results)
+		checkEnumWithID(id, "object", "RoomType", lot.RoomType, cianRoomTypes, results)
+		checkEnumWithID(id, "object", "WindowsViewType", lot.WindowsViewType, cianWindowsViewTypes, results)
+	}),
+	LotRule("cian/counts", func(lot Object, results *Results) {
+		if lot.BalconiesCount < 0 || lot.LoggiasCount < 0 || lot.SeparateWcsCount < 0 || lot.CombinedWcsCount < 0 {
+			results.Add("object.BalconiesCount", fmt.Sprintf("fields BalconiesCount, LoggiasCount, SeparateWcsCount and CombinedWcsCount must not be negative. InternalID: %v", lot.ExternalId))
+		}
+	}),
+	LotRule("cian/undergrounds", func(lot Object, results *Results) {
+		id := lot.ExternalId
+		for idx, underground := range lot.Undergrounds.UndergroundInfoSchema {
+			path := fmt.Sprintf("object.Undergrounds.UndergroundInfoSchema[%d]", idx)
+			checkZeroWithID(id, path, "Id", int(underground.ID), results)
+			checkZeroWithID(id, path, "Time", int(underground.Time), results)
+			if checkStringWithID(id, path, "TransportType", underground.TransportType, results) {
+				checkEnumWithID(id, path, "TransportType", underground.TransportType, cianUndergroundTransport, results)
+			}
+		}
+	}),
+	feedRule("cian/descriptions", func(f *CianFeed, run *checkRun, results *Results) {
+		ids := make([]string, len(f.Object))
+		descriptions := make([]string, len(f.Object))
+		for idx, lot := range f.Object {
+			ids[idx], descriptions[idx] = lot.ExternalId, lot.Description
+		}
+		checkDescriptions(run.options.descriptionRule(PlatformCian), "object", "Description", ids, descriptions, results)
+	}),
+	FeedRule("cian/unknown", func(f *CianFeed, results *Results) {
+		if f.Strict {
+			f.checkUnknown(results)
+		}
+	}),
+}
+
+// CheckUnknown reports elements and attributes of the feed and its objects that are not modelled by CianFeed.
+func (f *CianFeed) CheckUnknown() (results []string) {
+	unknown := &Results{lot: -1}
+	f.checkUnknown(unknown)
+	return unknown.messages()
+}
+
+func (f *CianFeed) checkUnknown(results *Results) {
+	checkUnknownWithID("", -1, "feed", f.Unknown, f.UnknownAttr, results)
+	for idx, lot := range f.Object {
+		checkUnknownWithID(lot.ExternalId, idx, "object", lot.Unknown, lot.UnknownAttr, results)
 	}
-	defer resp.Body.Close()
+}
 
-	err = statusCodeHandler(resp)
-	if err != nil {
-		return err
+// DiscardUnknown drops the unmodelled elements and attributes, so Marshal writes only known fields.
+func (f *CianFeed) DiscardUnknown() {
+	f.Unknown, f.UnknownAttr = nil, nil
+	for i := range f.Object {
+		f.Object[i].Unknown, f.Object[i].UnknownAttr = nil, nil
 	}
+}
+
+func checkCianPhones(lot Object, results *Results) {
+	id := lot.ExternalId
+
+	if len(lot.Phones.PhoneSchema) == 0 {
+		results.Add("object.Phones.PhoneSchema", fmt.Sprintf("field object.Phones.PhoneSchema is empty. InternalID: %v", id))
+		return
+	}
+
+	numbers := make(map[string]bool)
+	for idx, phone := range lot.Phones.PhoneSchema {
+		path := fmt.Sprintf("object.Phones.PhoneSchema[%d]", idx)
+		checkStringWithID(id, path, "CountryCode", phone.CountryCode, results)
+		if !checkStringWithID(id, path, "Number", phone.Number, results) {
+			continue
+		}
 
-	AttributeLastModified := resp.Header.Get("Last-Modified")
-	if AttributeLastModified != "" {
-		lastModifiedDate, err := time.Parse(time.RFC1123, resp.Header.Get("Last-Modified"))
-		if err != nil {
-			return err
+		for _, r := range phone.Number {
+			if r < '0' || r > '9' {
+				results.Add(path+".Number", fmt.Sprintf("field %s.Number contains non-digit characters: '%v'. InternalID: %v", path, phone.Number, id))
+				break
+			}
+		}
+		if phone.CountryCode == "+7" && len(phone.Number) != 10 {
+			results.Add(path+".Number", fmt.Sprintf("field %s.Number must contain 10 digits for CountryCode +7: '%v'. InternalID: %v", path, phone.Number, id))
 		}
-		f.LastModified = lastModifiedDate
-	} else {
-		log.Println("Header not contains `Last-Modified`")
+
+		if numbers[phone.CountryCode+phone.Number] {
+			results.Add(path+".Number", fmt.Sprintf("field %s.Number is duplicated: '%v'. InternalID: %v", path, phone.Number, id))
+		}
+		numbers[phone.CountryCode+phone.Number] = true
 	}
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
+}
+
+func checkCianLayoutPhotos(lot Object, results *Results) {
+	id := lot.ExternalId
+
+	if len(lot.LayoutPhoto) == 0 {
+		results.Add("object.LayoutPhoto", fmt.Sprintf("field object.LayoutPhoto is empty. InternalID: %v", id))
+		return
 	}
-	err = xml.Unmarshal(responseBody, &f)
-	if err != nil {
-		return err
+
+	for idx, photo := range lot.LayoutPhoto {
+		checkStringWithID(id, fmt.Sprintf("object.LayoutPhoto[%d]", idx), "FullUrl", photo.FullUrl, results)
 	}
-	//TODO Исправить значение f.LastModified
-	return nil
 }
 
-func (f *CianFeed) Check() (results []string) {
-	if len(f.Object) < 2 {
-		results = append(results, emptyFeed)
-		return results
+// cianCategories maps Cian object categories to the lot category, deal type and market.
+var cianCategories = []struct {
+	Value       string
+	Category    LotCategory
+	Deal        DealType
+	NewBuilding bool
+}{
+	{"newBuildingFlatSale", LotCategoryFlat, DealTypeSale, true},
+	{"flatSale", LotCategoryFlat, DealTypeSale, false},
+	{"flatRent", LotCategoryFlat, DealTypeRent, false},
+	{"roomSale", LotCategoryRoom, DealTypeSale, false},
+	{"roomRent", LotCategoryRoom, DealTypeRent, false},
+	{"houseSale", LotCategoryHouse, DealTypeSale, false},
+	{"houseRent", LotCategoryHouse, DealTypeRent, false},
+	{"cottageSale", LotCategoryHouse, DealTypeSale, false},
+	{"townhouseSale", LotCategoryHouse, DealTypeSale, false},
+	{"landSale", LotCategoryLand, DealTypeSale, false},
+	{"garageSale", LotCategoryGarage, DealTypeSale, false},
+	{"garageRent", LotCategoryGarage, DealTypeRent, false},
+	{"freeAppointmentObjectSale", LotCategoryCommercial, DealTypeSale, false},
+	{"freeAppointmentObjectRent", LotCategoryCommercial, DealTypeRent, false},
+	{"officeSale", LotCategoryCommercial, DealTypeSale, false},
+	{"officeRent", LotCategoryCommercial, DealTypeRent, false},
+	{"shoppingAreaSale", LotCategoryCommercial, DealTypeSale, false},
+	{"warehouseSale", LotCategoryCommercial, DealTypeSale, false},
+}
+
+// cianCategoryValues returns the categories that can be converted to lots.
+func cianCategoryValues() (values []string) {
+	for _, category := range cianCategories {
+		values = append(values, category.Value)
 	}
+	return values
+}
+
+var cianDecorations = enumTable[cianDecoration, Renovation]{
+	{cianDecorationWithout, RenovationNone},
+	{cianDecorationRough, RenovationRough},
+	{cianDecorationPreFine, RenovationPreFine},
+	{cianDecorationFine, RenovationFine},
+}
+
+var cianMaterialTypes = enumTable[string, BuildingMaterial]{
+	{"brick", BuildingMaterialBrick},
+	{"monolith", BuildingMaterialMonolith},
+	{"monolithBrick", BuildingMaterialMonolithBrick},
+	{"panel", BuildingMaterialPanel},
+	{"block", BuildingMaterialBlock},
+	{"aerocreteBlock", BuildingMaterialBlock},
+	{"foamConcreteBlock", BuildingMaterialBlock},
+	{"gasSilicateBlock", BuildingMaterialBlock},
+	{"wood", BuildingMaterialWood},
+}
+
+var cianQuarters = enumTable[cianQuarter, string]{
+	{cianQuarterFirst, "1"},
+	{cianQuarterSecond, "2"},
+	{cianQuarterThird, "3"},
+	{cianQuarterFourth, "4"},
+}
 
-	if len(f.Object) <= 10 {
-		results = append(results, fmt.Sprintf("feed contains only %v items", len(f.Object)))
-		return results
+const (
+	cianRoomsOpenPlan = 7
+	cianRoomsStudio   = 9
+)
+
+// cianBuilding returns the key and name of the building of the object: JKSchema.House.ID, or its name
+// when there is no ID, within JKSchema.ID. The name is the house name followed by the ID, e.g. "Корпус 1 (1234)".
+// ok is false for objects without a building.
+func cianBuilding(lot Object) (key string, name string, ok bool) {
+	house := lot.JKSchema.House
+	key, name = house.Name, house.Name
+	if house.ID != 0 {
+		key, name = strconv.Itoa(int(house.ID)), strconv.Itoa(int(house.ID))
+		if house.Name != "" {
+			name = fmt.Sprintf("%s (%d)", house.Name, house.ID)
+		}
 	}
-	for idx, lot := range f.Object {
-		id := lot.ExternalId
+	if key == "" {
+		return "", "", false
+	}
+	return fmt.Sprintf("%d/%s", lot.JKSchema.ID, key), name, true
+}
 
-		if lot.ExternalId == "" {
-			results = append(results, fmt.Sprintf("field ExternalId is empty. Position: %v", idx))
+// cianDeadline formats the deadline of the building of an object for comparison; it is empty when not set.
+func cianDeadline(lot Object) string {
+	deadline := lot.Building.Deadline
+	if deadline.Quarter == "" && deadline.Year == 0 {
+		return ""
+	}
+	if deadline.IsComplete {
+		return fmt.Sprintf("%s %d (complete)", deadline.Quarter, deadline.Year)
+	}
+	return fmt.Sprintf("%s %d", deadline.Quarter, deadline.Year)
+}
+
+// Lots returns the objects of the feed as canonical lots.
+func (f *CianFeed) Lots() (lots []Lot, results []string) {
+	for _, object := range f.Object {
+		id := object.ExternalId
+		lot := Lot{
+			Source:        PlatformCian,
+			ID:            id,
+			Apartments:    object.IsApartments,
+			Description:   object.Description,
+			Address:       object.Address,
+			Latitude:      float64(object.Coordinates.Lat),
+			Longitude:     float64(object.Coordinates.Lng),
+			Price:         object.BargainTerms.Price.Float64,
+			Currency:      object.BargainTerms.Currency,
+			Area:          float64(object.TotalArea),
+			LivingArea:    float64(object.LivingArea),
+			KitchenArea:   float64(object.KitchenArea),
+			CeilingHeight: float64(object.CeilingHeight),
+			Floor:         int(object.FloorNumber),
+			FloorsTotal:   int(object.Building.FloorsCount),
+			ComplexName:   object.JKSchema.Name,
+			BuildingName:  object.JKSchema.House.Name,
+			Section:       object.JKSchema.House.Flat.SectionNumber,
+			FlatNumber:    object.JKSchema.House.Flat.FlatNumber,
+			BuiltYear:     int(object.Building.Deadline.Year),
+			Finished:      object.Building.Deadline.IsComplete,
+		}
+		if object.JKSchema.ID != 0 {
+			lot.ComplexID = strconv.Itoa(int(object.JKSchema.ID))
+		}
+		if object.JKSchema.House.ID != 0 {
+			lot.BuildingID = strconv.Itoa(int(object.JKSchema.House.ID))
+		}
+		if len(object.Phones.PhoneSchema) > 0 {
+			lot.Phone = object.Phones.PhoneSchema[0].CountryCode + object.Phones.PhoneSchema[0].Number
 		}
-		checkStringWithID(id, "object", "Address", lot.Address, &results)
-		checkStringWithID(id, "object.Phones.PhoneSchema", "CountryCode", lot.Phones.PhoneSchema.CountryCode, &results)
-		checkStringWithID(id, "object.Phones.PhoneSchema", "Number", lot.Phones.PhoneSchema.Number, &results)
-		checkStringWithID(id, "object.LayoutPhoto.FullUrl", "IsDefault", lot.LayoutPhoto.FullUrl, &results)
-		checkStringWithID(id, "object", "Category", lot.Category, &results)
 
-		for idx, photoSchema := range lot.Photos.PhotoSchema {
-			checkStringWithPos(idx, "object.Photos.PhotoSchema", "FullUrl", photoSchema.FullUrl, &results)
+		found := false
+		for _, category := range cianCategories {
+			if category.Value == object.Category {
+				lot.Category, lot.Deal, lot.NewBuilding = category.Category, category.Deal, category.NewBuilding
+				found = true
+				break
+			}
+		}
+		if !found && object.Category != "" {
+			results = append(results, fmt.Sprintf("field object.Category value '%v' can not be mapped. InternalID: %v", object.Category, id))
 		}
 
-		checkZeroWithID(id, "object", "FlatRoomsCount", int(lot.FlatRoomsCount), &results)
-		checkZeroWithID(id, "object", "TotalArea", int(lot.TotalArea), &results)
-		checkZeroWithID(id, "object", "FloorNumber", int(lot.FloorNumber), &results)
-		checkZeroWithID(id, "object.Building", "FloorsCount", int(lot.Building.FloorsCount), &results)
-		checkZeroWithID(id, "object.Building.Deadline", "Year", int(lot.Building.Deadline.Year), &results)
-		checkStringWithID(id, "object.Building.Deadline", "Quarter", lot.Building.Deadline.Quarter, &results)
-		checkZeroWithID(id, "object.BargainTerms.Price", "Price", int(lot.BargainTerms.Price.Float64), &results)
-		checkZeroWithID(id, "object.JKSchema", "Id", int(lot.JKSchema.ID), &results)
-		checkStringWithID(id, "object.JKSchema", "Name", lot.JKSchema.Name, &results)
-		checkZeroWithID(id, "object.JKSchema.House", "Id", int(lot.JKSchema.House.ID), &results)
-		checkStringWithID(id, "object.JKSchema.House", "Name", lot.JKSchema.House.Name, &results)
+		switch object.FlatRoomsCount {
+		case cianRoomsStudio:
+			lot.Studio = true
+		case cianRoomsOpenPlan:
+			lot.OpenPlan = true
+		default:
+			lot.Rooms = int(object.FlatRoomsCount)
+		}
 
-		if lot.Building.Deadline.Year < int64(time.Now().Year()) && lot.Building.Deadline.IsComplete == false {
-			results = append(results, fmt.Sprintf("field Building.Deadline is False for %v. InternalID: %v", lot.Building.Deadline.Year, lot.ExternalId))
+		lot.Renovation = mapFromPlatform(cianDecorations, id, "object.Decoration", object.Decoration, &results)
+		lot.Material = mapFromPlatform(cianMaterialTypes, id, "object.Building.MaterialType", object.Building.MaterialType, &results)
+		if quarter := mapFromPlatform(cianQuarters, id, "object.Building.Deadline.Quarter", object.Building.Deadline.Quarter, &results); quarter != "" {
+			lot.ReadyQuarter, _ = strconv.Atoi(quarter)
 		}
-		if lot.FloorNumber > lot.Building.FloorsCount {
-			results = append(results, fmt.Sprintf("field FloorNumber is greater than Building.FloorsCount. InternalID: %v", lot.ExternalId))
+
+		switch {
+		case object.BalconiesCount > 0 && object.LoggiasCount > 0:
+			lot.Balcony = BalconyBoth
+		case object.BalconiesCount > 0:
+			lot.Balcony = BalconyBalcony
+		case object.LoggiasCount > 0:
+			lot.Balcony = BalconyLoggia
 		}
-		if len(lot.Photos.PhotoSchema) < 3 {
-			results = append(results, fmt.Sprintf("field Photos.PhotoSchema contains '%v' items. InternalID: %v", len(lot.Photos.PhotoSchema), lot.ExternalId))
+
+		for _, photo := range object.Photos.PhotoSchema {
+			if photo.IsDefault {
+				lot.Images = append([]string{photo.FullUrl}, lot.Images...)
+			} else {
+				lot.Images = append(lot.Images, photo.FullUrl)
+			}
 		}
+		for _, photo := range object.LayoutPhoto {
+			lot.PlanImages = append(lot.PlanImages, photo.FullUrl)
+		}
+		lots = append(lots, lot)
 	}
+	return lots, results
+}
 
+// FromLots replaces the objects of the feed with the given lots.
+func (f *CianFeed) FromLots(lots []Lot) (results []string) {
+	if f.FeedVersion == "" {
+		f.FeedVersion = "2"
+	}
+
+	f.Object = nil
+	for _, lot := range lots {
+		object := Object{
+			ExternalId:    lot.ID,
+			Description:   lot.Description,
+			Address:       lot.Address,
+			TotalArea:     float32(lot.Area),
+			LivingArea:    float32(lot.LivingArea),
+			KitchenArea:   float32(lot.KitchenArea),
+			CeilingHeight: float32(lot.CeilingHeight),
+			FloorNumber:   int64(lot.Floor),
+			IsApartments:  lot.Apartments,
+		}
+		object.Coordinates.Lat = float32(lot.Latitude)
+		object.Coordinates.Lng = float32(lot.Longitude)
+		object.BargainTerms.Price.Float64 = lot.Price
+		object.BargainTerms.Currency = "rur"
+		if lot.Currency != "" && !strings.EqualFold(lot.Currency, "RUB") && !strings.EqualFold(lot.Currency, "RUR") {
+			object.BargainTerms.Currency = strings.ToLower(lot.Currency)
+		}
+		object.Building.FloorsCount = int64(lot.FloorsTotal)
+		object.Building.Deadline.Year = int64(lot.BuiltYear)
+		object.Building.Deadline.IsComplete = lot.Finished
+		object.JKSchema.Name = lot.ComplexName
+		object.JKSchema.House.Name = lot.BuildingName
+		object.JKSchema.House.Flat.SectionNumber = lot.Section
+		object.JKSchema.House.Flat.FlatNumber = lot.FlatNumber
+
+		if complexID := copyCatalogID(lot, PlatformCian, "ComplexID", lot.ComplexID, &results); complexID != "" {
+			id, _ := strconv.Atoi(complexID)
+			object.JKSchema.ID = int32(id)
+		}
+		if buildingID := copyCatalogID(lot, PlatformCian, "BuildingID", lot.BuildingID, &results); buildingID != "" {
+			id, _ := strconv.Atoi(buildingID)
+			object.JKSchema.House.ID = int32(id)
+		}
+
+		if lot.Phone != "" {
+			countryCode, number := splitPhone(lot.Phone)
+			object.Phones.PhoneSchema = []PhoneSchema{{CountryCode: countryCode, Number: number}}
+		}
+
+		found := false
+		for _, category := range cianCategories {
+			if category.Category == lot.Category && category.Deal == lot.Deal && category.NewBuilding == lot.NewBuilding {
+				object.Category = category.Value
+				found = true
+				break
+			}
+		}
+		if !found {
+			results = append(results, fmt.Sprintf("field Category value '%v %v' can not be mapped to %s. InternalID: %v", lot.Category, lot.Deal, PlatformCian, lot.ID))
+		}
+
+		switch {
+		case lot.Studio:
+			object.FlatRoomsCount = cianRoomsStudio
+			object.JKSchema.House.Flat.FlatType = string(cianFlatTypeStudio)
+		case lot.OpenPlan:
+			object.FlatRoomsCount = cianRoomsOpenPlan
+			object.JKSchema.House.Flat.FlatType = string(cianFlatTypeOpenPlan)
+		case lot.Rooms > 0:
+			object.FlatRoomsCount = int64(lot.Rooms)
+			object.JKSchema.House.Flat.FlatType = string(cianFlatTypeRooms)
+		}
+
+		object.Decoration = mapToPlatform(cianDecorations, PlatformCian, lot.ID, "Renovation", lot.Renovation, &results)
+		object.Building.MaterialType = mapToPlatform(cianMaterialTypes, PlatformCian, lot.ID, "Material", lot.Material, &results)
+		if lot.ReadyQuarter != 0 {
+			object.Building.Deadline.Quarter = mapToPlatform(cianQuarters, PlatformCian, lot.ID, "ReadyQuarter", strconv.Itoa(lot.ReadyQuarter), &results)
+		}
+
+		switch lot.Balcony {
+		case BalconyBalcony:
+			object.BalconiesCount = 1
+		case BalconyLoggia:
+			object.LoggiasCount = 1
+		case BalconyBoth:
+			object.BalconiesCount, object.LoggiasCount = 1, 1
+		}
+
+		for idx, url := range lot.Images {
+			object.Photos.PhotoSchema = append(object.Photos.PhotoSchema, PhotoSchema{FullUrl: url, IsDefault: idx == 0})
+		}
+		for _, url := range lot.PlanImages {
+			object.LayoutPhoto = append(object.LayoutPhoto, PhotoSchema{FullUrl: url})
+		}
+		f.Object = append(f.Object, object)
+	}
 	return results
 }
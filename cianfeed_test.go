@@ -0,0 +1,158 @@
+package price_placements_feeds
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// testCianFeed builds objects of one house of a complex, all finishing in Q4 2025.
+func testCianFeed(objects int) *CianFeed {
+	feed := &CianFeed{LastModified: testNow}
+	for i := 0; i < objects; i++ {
+		var object Object
+		object.ExternalId = fmt.Sprintf("obj-%d", i)
+		object.Address = "Москва, ул. Парковая, 1"
+		object.JKSchema.ID = 10
+		object.JKSchema.Name = "ЖК Парк"
+		object.JKSchema.House.Name = "Корпус 1"
+		object.Building.FloorsCount = 9
+		object.Building.Deadline.Quarter = "fourth"
+		object.Building.Deadline.Year = 2025
+		feed.Object = append(feed.Object, object)
+	}
+	return feed
+}
+
+func TestCianDeadline(t *testing.T) {
+	feed := testCianFeed(4)
+	feed.Object[1].Building.Deadline.Quarter = "fifth"
+	feed.Object[2].Building.Deadline.Year = 2040
+	feed.Object[3].Building.Deadline.Quarter = "first"
+	feed.Object[3].Building.Deadline.Year = 2024
+
+	var got []string
+	for _, issue := range DefaultRules.Only("cian/deadline").CheckIssues(feed, CheckOptions{Now: testClock}) {
+		got = append(got, issue.LotID+" "+issue.Field)
+	}
+	want := []string{
+		"obj-1 object.Building.Deadline.Quarter",
+		"obj-2 object.Building.Deadline.Year",
+		"obj-3 object.Building.Deadline.IsComplete",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %q, want %q", got, want)
+	}
+
+	feed.Object[3].Building.Deadline.IsComplete = true
+	if results := DefaultRules.Only("cian/deadline").CheckWith(feed, CheckOptions{Now: testClock}); len(results) != 2 {
+		t.Errorf("complete building is reported: %q", results)
+	}
+}
+
+func TestCianBuildingConsistencyComparesDeadlines(t *testing.T) {
+	feed := testCianFeed(4)
+	feed.Object[2].Building.Deadline.Year = 2026
+
+	issues := DefaultRules.Only("cian/*-consistency").CheckIssues(feed, CheckOptions{Now: testClock})
+	if len(issues) != 1 {
+		t.Fatalf("issues = %+v", issues)
+	}
+	issue := issues[0]
+	if issue.Rule != "cian/building-consistency" || issue.Field != "object.Building.Deadline" || issue.LotID != "obj-2" {
+		t.Errorf("issue = %+v", issue)
+	}
+	if names := DefaultRules.Only("cian/deadline-consistency").Names(); names != nil {
+		t.Errorf("separate deadline consistency rule is registered: %q", names)
+	}
+}
+
+func TestCianPhonesAndPhotosAreLists(t *testing.T) {
+	data := `<feed><feed_version>2</feed_version><object><ExternalId>1</ExternalId>` +
+		`<Phones><PhoneSchema><CountryCode>+7</CountryCode><Number>4951234567</Number></PhoneSchema>` +
+		`<PhoneSchema><CountryCode>+7</CountryCode><Number>4957654321</Number></PhoneSchema></Phones>` +
+		`<LayoutPhoto><FullUrl>https://example.com/plan1.jpg</FullUrl></LayoutPhoto>` +
+		`<LayoutPhoto><FullUrl>https://example.com/plan2.jpg</FullUrl></LayoutPhoto>` +
+		`</object></feed>`
+	var feed CianFeed
+	if err := xml.Unmarshal([]byte(data), &feed); err != nil {
+		t.Fatal(err)
+	}
+	object := feed.Object[0]
+	if len(object.Phones.PhoneSchema) != 2 || object.Phones.PhoneSchema[1].Number != "4957654321" {
+		t.Errorf("phones = %+v", object.Phones.PhoneSchema)
+	}
+	if len(object.LayoutPhoto) != 2 || object.LayoutPhoto[1].FullUrl != "https://example.com/plan2.jpg" {
+		t.Errorf("layout photos = %+v", object.LayoutPhoto)
+	}
+}
+
+func TestCianPhones(t *testing.T) {
+	feed := testCianFeed(4)
+	feed.Object[0].Phones.PhoneSchema = []PhoneSchema{{"+7", "4951234567"}, {"+7", "4957654321"}}
+	feed.Object[1].Phones.PhoneSchema = []PhoneSchema{{"+7", "495-123-4"}, {"", "4951234567"}}
+	feed.Object[2].Phones.PhoneSchema = []PhoneSchema{{"+7", "4951234567"}, {"+7", "4951234567"}}
+
+	want := []string{
+		"field object.Phones.PhoneSchema[0].Number contains non-digit characters: '495-123-4'. InternalID: obj-1",
+		"field object.Phones.PhoneSchema[0].Number must contain 10 digits for CountryCode +7: '495-123-4'. InternalID: obj-1",
+		"field object.Phones.PhoneSchema[1].CountryCode is empty. InternalID: obj-1",
+		"field object.Phones.PhoneSchema[1].Number is duplicated: '4951234567'. InternalID: obj-2",
+		"field object.Phones.PhoneSchema is empty. InternalID: obj-3",
+	}
+	if got := DefaultRules.Only("cian/phones").CheckWith(feed, CheckOptions{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckWith() = %q, want %q", got, want)
+	}
+}
+
+func TestCianDefaultPhoto(t *testing.T) {
+	photos := func(defaults ...bool) (schema []PhotoSchema) {
+		for idx, isDefault := range defaults {
+			schema = append(schema, PhotoSchema{FullUrl: fmt.Sprintf("https://example.com/%d.jpg", idx), IsDefault: isDefault})
+		}
+		return schema
+	}
+	feed := testCianFeed(3)
+	for idx := range feed.Object {
+		feed.Object[idx].LayoutPhoto = photos(false)
+	}
+	feed.Object[0].Photos.PhotoSchema = photos(true, false, false)
+	feed.Object[1].Photos.PhotoSchema = photos(false, false, false)
+	feed.Object[2].Photos.PhotoSchema = photos(true, true, false)
+
+	want := []string{
+		"field Photos.PhotoSchema contains '0' default photos, expected 1. InternalID: obj-1",
+		"field Photos.PhotoSchema contains '2' default photos, expected 1. InternalID: obj-2",
+	}
+	if got := DefaultRules.Only("cian/photos").CheckWith(feed, CheckOptions{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckWith() = %q, want %q", got, want)
+	}
+}
+
+func TestCianNewBuildingFields(t *testing.T) {
+	feed := testCianFeed(2)
+	feed.Object[0].JKSchema.House.Flat.FlatType = "openPlan"
+	feed.Object[0].WindowsViewType = "yardAndStreet"
+	feed.Object[0].Undergrounds.UndergroundInfoSchema = []UndergroundInfoSchema{{TransportType: "walk", Time: 10, ID: 1}}
+	feed.Object[1].JKSchema.House.Flat.FlatType = "loft"
+	feed.Object[1].WindowsViewType = "sea"
+	feed.Object[1].LoggiasCount = -1
+	feed.Object[1].Undergrounds.UndergroundInfoSchema = []UndergroundInfoSchema{{TransportType: "walk", Time: 10, ID: 1}, {TransportType: "bus"}}
+
+	var got []string
+	for _, issue := range DefaultRules.Only("cian/enums", "cian/counts", "cian/undergrounds").CheckIssues(feed, CheckOptions{}) {
+		got = append(got, issue.LotID+" "+issue.Field)
+	}
+	want := []string{
+		"obj-1 object.JKSchema.House.Flat.FlatType",
+		"obj-1 object.WindowsViewType",
+		"obj-1 object.BalconiesCount",
+		"obj-1 object.Undergrounds.UndergroundInfoSchema[].Id",
+		"obj-1 object.Undergrounds.UndergroundInfoSchema[].Time",
+		"obj-1 object.Undergrounds.UndergroundInfoSchema[].TransportType",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %q, want %q", got, want)
+	}
+}
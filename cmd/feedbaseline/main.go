@@ -0,0 +1,73 @@
+// Command feedbaseline checks a feed and writes its current issues to a baseline file,
+// so that they are suppressed in later reports. Entries already in the file are kept.
+//
+// Usage:
+//
+//	feedbaseline -platform cian -feed https://example.com/cian.xml -out baseline.json -reason "accepted by the client" -expires 2024-12-31
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	feeds "github.com/mg-realcom/price-placements"
+)
+
+func main() {
+	platform := flag.String("platform", "", "feed platform: avito, cian, domclick or realty")
+	source := flag.String("feed", "", "feed URL or file")
+	out := flag.String("out", "baseline.json", "path to the baseline file")
+	reason := flag.String("reason", "accepted", "reason written to the new entries")
+	expires := flag.String("expires", "", "expiry date of the new entries, e.g. 2024-12-31")
+	flag.Parse()
+
+	if *platform == "" || *source == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *expires != "" {
+		if _, err := time.Parse("2006-01-02", *expires); err != nil {
+			log.Fatalf("expires must look like 2024-12-31: %s", *expires)
+		}
+	}
+
+	feed, err := loadFeed(feeds.Platform(*platform), *source)
+	if err != nil {
+		log.Fatalf("can't load feed: %v", err)
+	}
+
+	baseline := &feeds.Baseline{}
+	if _, err := os.Stat(*out); err == nil {
+		baseline, err = feeds.LoadBaseline(*out)
+		if err != nil {
+			log.Fatalf("can't load baseline: %v", err)
+		}
+	}
+	known := len(baseline.Entries)
+
+	issues := feeds.DefaultRules.CheckIssues(feed, feeds.CheckOptions{})
+	baseline.Merge(feeds.NewBaseline(issues, *reason, *expires))
+	if err := baseline.Save(*out); err != nil {
+		log.Fatalf("can't save baseline: %v", err)
+	}
+	log.Printf("%d issues, %d new baseline entries, %d entries in %s", len(issues), len(baseline.Entries)-known, len(baseline.Entries), *out)
+}
+
+func loadFeed(platform feeds.Platform, source string) (feeds.Feed, error) {
+	feed, err := feeds.NewFeed(platform)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return feed, feed.Get(source)
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+	return feed, xml.Unmarshal(data, feed)
+}
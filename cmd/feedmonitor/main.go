@@ -0,0 +1,81 @@
+// Command feedmonitor polls the feeds listed in a JSON config, logs feed transitions and sends alerts.
+//
+// Usage:
+//
+//	feedmonitor -config projects.json -store results.json
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	feeds "github.com/mg-realcom/price-placements"
+)
+
+func main() {
+	configPath := flag.String("config", "projects.json", "path to the projects config")
+	storePath := flag.String("store", "results.json", "path to the file with the latest results")
+	flag.Parse()
+
+	config, err := feeds.LoadMonitorConfig(*configPath)
+	if err != nil {
+		log.Fatalf("can't load config: %v", err)
+	}
+
+	store, err := feeds.NewFileStore(*storePath)
+	if err != nil {
+		log.Fatalf("can't open store: %v", err)
+	}
+
+	alerter, err := feeds.NewAlerter(config.Alerts)
+	if err != nil {
+		log.Fatalf("can't configure alerts: %v", err)
+	}
+
+	monitor := feeds.NewMonitor(config, store)
+	if config.Baseline != "" {
+		monitor.Baseline, err = feeds.LoadBaseline(config.Baseline)
+		if err != nil {
+			log.Fatalf("can't load baseline: %v", err)
+		}
+	}
+	monitor.OnResult = func(result feeds.FeedResult) {
+		if result.Error != "" {
+			log.Printf("%s %s: %s", result.Project, result.Platform, result.Error)
+			return
+		}
+		log.Printf("%s %s: %d lots, %d issues, %d suppressed", result.Project, result.Platform, result.LotCount, len(result.Issues), result.Suppressed)
+	}
+	monitor.OnEvent = func(event feeds.Event) {
+		log.Printf("%s %s: %s %v", event.Current.Project, event.Current.Platform, event.Type, event.Issues)
+		alerter.OnEvent(event)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Send the alerts held back by quiet hours when they are over, even if no new events come.
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := alerter.Flush(ctx); err != nil {
+					log.Printf("alerts held back by quiet hours: %v", err)
+				}
+			}
+		}
+	}()
+
+	if err := monitor.Run(ctx); err != nil && err != context.Canceled {
+		log.Fatal(err)
+	}
+}
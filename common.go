@@ -3,7 +3,6 @@ package price_placements_feeds
 import (
 	"encoding/xml"
 	"fmt"
-	"net/http"
 	"strconv"
 )
 
@@ -11,16 +10,6 @@ const (
 	emptyFeed string = "feed is empty"
 )
 
-func statusCodeHandler(resp *http.Response) error {
-	if resp == nil {
-		return fmt.Errorf("не могу получить ответ сервера")
-	}
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("не могу получить ответ сервера. Statuscode: %v", resp.StatusCode)
-	}
-	return nil
-}
-
 type CustomInt64 struct {
 	Int64 int64
 	Valid bool
@@ -43,26 +32,35 @@ func (ci *CustomInt64) UnmarshalXML(d *xml.Decoder, start xml.StartElement) erro
 	}
 
 	ci.Int64 = int64(customI)
+	ci.Valid = true
 	return nil
 }
 
-func checkString(path string, fieldName string, value string, results *[]string) (isOk bool) {
+func (ci CustomInt64) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if !ci.Valid {
+		return nil
+	}
+	return e.EncodeElement(ci.Int64, start)
+}
+
+func checkString(path string, fieldName string, value string, results *Results) (isOk bool) {
 	if value == "" {
-		*results = append(*results, fmt.Sprintf("field %s.%s is empty", path, fieldName))
+		results.Add(path+"."+fieldName, fmt.Sprintf("field %s.%s is empty", path, fieldName))
 		return false
 	}
 	return true
 }
 
-func checkStringWithPos(idx int, path string, fieldName string, value string, results *[]string) (isOk bool) {
+func checkStringWithPos(idx int, path string, fieldName string, value string, results *Results) (isOk bool) {
 	if value == "" {
-		*results = append(*results, fmt.Sprintf("field %s[%d].%s is empty", path, idx, fieldName))
+		field := fmt.Sprintf("%s[%d].%s", path, idx, fieldName)
+		results.Add(field, fmt.Sprintf("field %s is empty", field))
 		return false
 	}
 	return true
 }
 
-func checkStringWithID(ID string, path string, fieldName string, value string, results *[]string) (isOk bool) {
+func checkStringWithID(ID string, path string, fieldName string, value string, results *Results) (isOk bool) {
 	var idMessage string
 	if ID == "" {
 		idMessage = "InternalID not found"
@@ -71,17 +69,25 @@ func checkStringWithID(ID string, path string, fieldName string, value string, r
 	}
 
 	if value == "" {
-		*results = append(*results, fmt.Sprintf("field %s.%s is empty. %s", path, fieldName, idMessage))
+		results.Add(path+"."+fieldName, fmt.Sprintf("field %s.%s is empty. %s", path, fieldName, idMessage))
 		return false
 	}
 
 	return true
 }
 
-func checkZeroWithID[V int | float64 | float32](ID string, path string, fieldName string, value V, results *[]string) (isOk bool) {
+func checkZeroWithID[V int | float64 | float32](ID string, path string, fieldName string, value V, results *Results) (isOk bool) {
 	if value == 0 {
-		*results = append(*results, fmt.Sprintf("field %s.%s is empty. InternalID: %s", path, fieldName, ID))
+		results.Add(path+"."+fieldName, fmt.Sprintf("field %s.%s is empty. InternalID: %s", path, fieldName, ID))
 		return false
 	}
 	return true
 }
+
+func checkEnumWithID(ID string, path string, fieldName string, value string, enum Enum, results *Results) (isOk bool) {
+	if value == "" || enum.Contains(value) {
+		return true
+	}
+	results.Add(path+"."+fieldName, enumMessage(ID, path, fieldName, value, enum))
+	return false
+}
@@ -0,0 +1,48 @@
+package price_placements_feeds
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// MaxDeadlineYears is how many years ahead of the check date a construction deadline may be.
+// Later deadlines are reported as likely typos.
+var MaxDeadlineYears = 10
+
+// quarterOf returns the quarter of a month, 1 to 4.
+func quarterOf(month int) int64 {
+	return int64(month-1)/3 + 1
+}
+
+// checkQuarterWithID reports a quarter out of the range 1–4. An empty quarter is left to the required field checks.
+func checkQuarterWithID(ID string, path string, fieldName string, quarter int64, results *Results) (isOk bool) {
+	if quarter == 0 || (quarter >= 1 && quarter <= 4) {
+		return true
+	}
+	results.Add(path+"."+fieldName, fmt.Sprintf("field %s.%s must be from 1 to 4, got %v. InternalID: %s", path, fieldName, quarter, ID))
+	return false
+}
+
+// checkDeadlineYearWithID reports a deadline more than MaxDeadlineYears ahead of the year of the check.
+func checkDeadlineYearWithID(ID string, path string, fieldName string, year int64, nowYear int, results *Results) (isOk bool) {
+	if year == 0 || year <= int64(nowYear+MaxDeadlineYears) {
+		return true
+	}
+	results.Add(path+"."+fieldName, fmt.Sprintf("field %s.%s is more than %d years ahead: %v. InternalID: %s", path, fieldName, MaxDeadlineYears, year, ID))
+	return false
+}
+
+// deadlinePassed returns the deadline of an unfinished building as text when it is in the past: the year
+// when the whole year has passed, or the quarter and year when only the quarter has. A zero quarter means
+// the year only; a quarter out of range is ignored.
+func deadlinePassed(year int64, quarter int64, nowYear int, nowQuarter int64) (deadline string, passed bool) {
+	switch {
+	case year == 0:
+		return "", false
+	case year < int64(nowYear):
+		return strconv.FormatInt(year, 10), true
+	case year == int64(nowYear) && quarter >= 1 && quarter < nowQuarter:
+		return fmt.Sprintf("Q%d %d", quarter, year), true
+	}
+	return "", false
+}
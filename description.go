@@ -0,0 +1,189 @@
+package price_placements_feeds
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DescriptionRule describes the descriptions a platform accepts. Zero values disable a check.
+type DescriptionRule struct {
+	// MinLength and MaxLength are measured in characters of the text without HTML tags.
+	MinLength int
+	MaxLength int
+	// AllowedTags are the HTML tags allowed in the text. Any tag is reported when it is empty.
+	AllowedTags []string
+	// NoPhones and NoURLs reject contacts written into the text.
+	NoPhones bool
+	NoURLs   bool
+	// MaxCapsPercent is the share of upper-case letters, in percent, above which the text is reported.
+	MaxCapsPercent float64
+	// StopWords are words and phrases the text must not contain. They are matched case-insensitively.
+	StopWords []string
+}
+
+// descriptionRules are the default description rules of the platforms, see DefaultDescriptionRules.
+var descriptionRules = map[Platform]DescriptionRule{
+	PlatformAvito: {
+		MinLength:      50,
+		MaxLength:      7500,
+		AllowedTags:    []string{"p", "br", "strong", "em", "ul", "ol", "li"},
+		NoPhones:       true,
+		NoURLs:         true,
+		MaxCapsPercent: 30,
+	},
+	PlatformCian: {
+		MinLength:      50,
+		MaxLength:      10000,
+		MaxCapsPercent: 30,
+	},
+	PlatformDomclick: {
+		MinLength:      50,
+		MaxLength:      10000,
+		MaxCapsPercent: 30,
+	},
+	PlatformRealty: {
+		MinLength:      50,
+		MaxLength:      10000,
+		MaxCapsPercent: 30,
+	},
+}
+
+// DefaultDescriptionRules returns a copy of the default description rules of the platforms, e.g. to add
+// stop-words and pass the rules in CheckOptions.Descriptions.
+func DefaultDescriptionRules() map[Platform]DescriptionRule {
+	rules := make(map[Platform]DescriptionRule, len(descriptionRules))
+	for platform, rule := range descriptionRules {
+		rule.AllowedTags = append([]string(nil), rule.AllowedTags...)
+		rule.StopWords = append([]string(nil), rule.StopWords...)
+		rules[platform] = rule
+	}
+	return rules
+}
+
+// descriptionRule returns the description rule of the platform in the options, or the default one.
+func (o CheckOptions) descriptionRule(platform Platform) DescriptionRule {
+	if rule, ok := o.Descriptions[platform]; ok {
+		return rule
+	}
+	return descriptionRules[platform]
+}
+
+var (
+	descriptionTag   = regexp.MustCompile(`<\s*/?\s*([a-zA-Z][a-zA-Z0-9]*)[^>]*>`)
+	descriptionPhone = regexp.MustCompile(`(?:\+7|\b8)[\s\-(]*\d{3}[\s\-)]*\d{3}[\s\-]*\d{2}[\s\-]*\d{2}\b`)
+	// descriptionURL matches links and bare domains. \b only knows ASCII letters, so the domains, which may be
+	// Cyrillic like "парк.рф", are bounded by any character that can't be part of them.
+	descriptionURL = regexp.MustCompile(`(?i)(?:https?://|www\.)\S+|(?:^|[^\p{L}\p{N}\-.])([\p{L}\p{N}\-]+(?:\.[\p{L}\p{N}\-]+)*\.(?:ru|su|com|net|org|рф))(?:$|[^\p{L}\p{N}\-])`)
+)
+
+// checkDescriptions checks the quality of lot descriptions and reports identical descriptions of different lots.
+// ids and texts are parallel slices indexed by lot position; empty texts are skipped.
+func checkDescriptions(rule DescriptionRule, path string, fieldName string, ids []string, texts []string, results *Results) {
+	seen := make(map[string]string)
+	for idx, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		checkDescription(rule, ids[idx], idx, path, fieldName, text, results)
+
+		key := strings.ToLower(strings.Join(strings.Fields(descriptionTag.ReplaceAllString(text, " ")), " "))
+		if first, ok := seen[key]; ok {
+			results.AddLot(idx, path+"."+fieldName, fmt.Sprintf("field %s.%s is the same as for InternalID %s. InternalID: %s", path, fieldName, first, ids[idx]))
+			continue
+		}
+		seen[key] = ids[idx]
+	}
+}
+
+// checkDescription checks the description of the lot at position, or of the feed for a negative position.
+func checkDescription(rule DescriptionRule, ID string, position int, path string, fieldName string, text string, results *Results) {
+	report := func(format string, args ...any) {
+		results.AddLot(position, path+"."+fieldName, fmt.Sprintf("field %s.%s %s. InternalID: %s", path, fieldName, fmt.Sprintf(format, args...), ID))
+	}
+
+	for _, match := range descriptionTag.FindAllStringSubmatch(text, -1) {
+		if !containsFold(rule.AllowedTags, match[1]) {
+			report("contains HTML tag <%s>", strings.ToLower(match[1]))
+			break
+		}
+	}
+
+	plain := strings.TrimSpace(descriptionTag.ReplaceAllString(text, " "))
+	length := len([]rune(plain))
+	if rule.MinLength > 0 && length < rule.MinLength {
+		report("is shorter than %d characters (%d)", rule.MinLength, length)
+	}
+	if rule.MaxLength > 0 && length > rule.MaxLength {
+		report("is longer than %d characters (%d)", rule.MaxLength, length)
+	}
+
+	if rule.NoPhones {
+		if phone := descriptionPhone.FindString(plain); phone != "" {
+			report("contains phone number '%s'", phone)
+		}
+	}
+	if rule.NoURLs {
+		if match := descriptionURL.FindStringSubmatch(plain); match != nil {
+			link := match[0]
+			if match[1] != "" {
+				link = match[1]
+			}
+			report("contains link '%s'", link)
+		}
+	}
+
+	if rule.MaxCapsPercent > 0 {
+		var letters, upper int
+		for _, r := range plain {
+			if unicode.IsLetter(r) {
+				letters++
+				if unicode.IsUpper(r) {
+					upper++
+				}
+			}
+		}
+		if letters >= 20 && float64(upper)*100/float64(letters) > rule.MaxCapsPercent {
+			report("has %d%% upper-case letters", upper*100/letters)
+		}
+	}
+
+	lower := strings.ToLower(plain)
+	for _, word := range rule.StopWords {
+		if word != "" && containsWord(lower, strings.ToLower(word)) {
+			report("contains stop-word '%s'", word)
+		}
+	}
+}
+
+// containsWord reports whether text contains word not surrounded by letters or digits.
+func containsWord(text string, word string) bool {
+	for offset := 0; ; {
+		idx := strings.Index(text[offset:], word)
+		if idx < 0 {
+			return false
+		}
+		start, end := offset+idx, offset+idx+len(word)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		offset = end
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
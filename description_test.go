@@ -0,0 +1,65 @@
+package price_placements_feeds
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDescriptionLinks(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Подробности на сайте парк.рф, звоните.", "парк.рф"},
+		{"Подробности на сайте ЖК-Парк.РФ.", "ЖК-Парк.РФ"},
+		{"Сайт: mail.example.com/park", "mail.example.com"},
+		{"Смотрите https://example.com/park?id=1", "https://example.com/park?id=1"},
+		{"Смотрите www.example.org", "www.example.org"},
+		{"Рядом files.russia и рфцентр", ""},
+		{"Отделка класса комфорт.рфк", ""},
+	}
+	rule := DescriptionRule{NoURLs: true}
+	for _, tt := range tests {
+		results := &Results{lot: -1}
+		checkDescription(rule, "1", -1, "Ad", "Description", tt.text, results)
+		var got []string
+		for _, issue := range results.issues {
+			got = append(got, issue.Message)
+		}
+		var want []string
+		if tt.want != "" {
+			want = []string{"field Ad.Description contains link '" + tt.want + "'. InternalID: 1"}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: issues = %q, want %q", tt.text, got, want)
+		}
+	}
+}
+
+func TestDescriptionRulesInOptions(t *testing.T) {
+	feed := testAvitoFeed(12)
+	feed.Ad[3].Description = strings.Repeat("Квартира без посредников, торг уместен. ", 3)
+
+	if results := DefaultRules.Only("avito/descriptions").CheckWith(feed, CheckOptions{}); results != nil {
+		t.Fatalf("default rules report %q", results)
+	}
+
+	rules := DefaultDescriptionRules()
+	avito := rules[PlatformAvito]
+	avito.StopWords = append(avito.StopWords, "без посредников")
+	rules[PlatformAvito] = avito
+	results := DefaultRules.Only("avito/descriptions").CheckWith(feed, CheckOptions{Descriptions: rules})
+	want := []string{"field Ad.Description contains stop-word 'без посредников'. InternalID: ad-3"}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("CheckWith() = %q, want %q", results, want)
+	}
+
+	rules[PlatformAvito].AllowedTags[0] = "script"
+	if DefaultDescriptionRules()[PlatformAvito].AllowedTags[0] != "p" {
+		t.Error("DefaultDescriptionRules() shares the default tags")
+	}
+	if results := DefaultRules.Only("avito/descriptions").CheckWith(feed, CheckOptions{}); results != nil {
+		t.Errorf("options changed the default rules: %q", results)
+	}
+}
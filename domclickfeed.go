@@ -4,231 +4,496 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
-	"log"
+	"strings"
 	"time"
 )
 
 type DomclickFeed struct {
-	LastModified time.Time
-	XMLName      xml.Name `xml:"complexes"`
+	LastModified time.Time `xml:"-"`
+	XMLName      xml.Name  `xml:"complexes"`
 	Complex      struct {
-		ID        string `xml:"id"`
-		Name      string `xml:"name"`
-		Latitude  string `xml:"latitude"`
-		Longitude string `xml:"longitude"`
-		Address   string `xml:"address"`
+		ID        string `xml:"id,omitempty"`
+		Name      string `xml:"name,omitempty"`
+		Latitude  string `xml:"latitude,omitempty"`
+		Longitude string `xml:"longitude,omitempty"`
+		Address   string `xml:"address,omitempty"`
 		Images    struct {
-			Image []string `xml:"image"`
-		} `xml:"images"`
+			Image []string `xml:"image,omitempty"`
+		} `xml:"images,omitempty"`
 		DescriptionMain struct {
-			Title string `xml:"title"`
-			Text  string `xml:"text"`
-		} `xml:"description_main"`
+			Title string `xml:"title,omitempty"`
+			Text  string `xml:"text,omitempty"`
+		} `xml:"description_main,omitempty"`
 		Infrastructure struct {
-			Parking      string `xml:"parking"`
-			Security     string `xml:"security"`
-			FencedArea   string `xml:"fenced_area"`
-			SportsGround string `xml:"sports_ground"`
-			Playground   string `xml:"playground"`
-			School       string `xml:"school"`
-			Kindergarten string `xml:"kindergarten"`
-		} `xml:"infrastructure"`
+			Parking      string `xml:"parking,omitempty"`
+			Security     string `xml:"security,omitempty"`
+			FencedArea   string `xml:"fenced_area,omitempty"`
+			SportsGround string `xml:"sports_ground,omitempty"`
+			Playground   string `xml:"playground,omitempty"`
+			School       string `xml:"school,omitempty"`
+			Kindergarten string `xml:"kindergarten,omitempty"`
+		} `xml:"infrastructure,omitempty"`
 		ProfitsMain struct {
 			ProfitMain []struct {
-				Title string `xml:"title"`
-				Text  string `xml:"text"`
-				Image string `xml:"image"`
-			} `xml:"profit_main"`
-		} `xml:"profits_main"`
+				Title string `xml:"title,omitempty"`
+				Text  string `xml:"text,omitempty"`
+				Image string `xml:"image,omitempty"`
+			} `xml:"profit_main,omitempty"`
+		} `xml:"profits_main,omitempty"`
 		ProfitsSecondary struct {
 			ProfitSecondary []struct {
-				Title string `xml:"title"`
-				Text  string `xml:"text"`
-				Image string `xml:"image"`
-			} `xml:"profit_secondary"`
-		} `xml:"profits_secondary"`
+				Title string `xml:"title,omitempty"`
+				Text  string `xml:"text,omitempty"`
+				Image string `xml:"image,omitempty"`
+			} `xml:"profit_secondary,omitempty"`
+		} `xml:"profits_secondary,omitempty"`
 		Buildings struct {
-			Building []struct {
-				ID            string `xml:"id"`
-				Fz214         string `xml:"fz_214"`
-				Name          string `xml:"name"`
-				Floors        int64  `xml:"floors"`
-				BuildingState string `xml:"building_state"`
-				BuiltYear     int64  `xml:"built_year"`
-				ReadyQuarter  int64  `xml:"ready_quarter"`
-				BuildingType  string `xml:"building_type"`
-				Image         string `xml:"image"`
-				Flats         struct {
-					Flat []Flat `xml:"flat"`
-				} `xml:"flats"`
-			} `xml:"building"`
-		} `xml:"buildings"`
+			Building []DomclickBuilding `xml:"building,omitempty"`
+		} `xml:"buildings,omitempty"`
 		SalesInfo struct {
-			SalesPhone              string `xml:"sales_phone"`
-			ResponsibleOfficerPhone string `xml:"responsible_officer_phone"`
-			SalesAddress            string `xml:"sales_address"`
-			SalesLatitude           string `xml:"sales_latitude"`
-			SalesLongitude          string `xml:"sales_longitude"`
-			Timezone                string `xml:"timezone"`
+			SalesPhone              string `xml:"sales_phone,omitempty"`
+			ResponsibleOfficerPhone string `xml:"responsible_officer_phone,omitempty"`
+			SalesAddress            string `xml:"sales_address,omitempty"`
+			SalesLatitude           string `xml:"sales_latitude,omitempty"`
+			SalesLongitude          string `xml:"sales_longitude,omitempty"`
+			Timezone                string `xml:"timezone,omitempty"`
 			WorkDays                struct {
 				WorkDay []struct {
-					Day     string `xml:"day"`
-					OpenAt  string `xml:"open_at"`
-					CloseAt string `xml:"close_at"`
-				} `xml:"work_day"`
-			} `xml:"work_days"`
-		} `xml:"sales_info"`
+					Day     string `xml:"day,omitempty"`
+					OpenAt  string `xml:"open_at,omitempty"`
+					CloseAt string `xml:"close_at,omitempty"`
+				} `xml:"work_day,omitempty"`
+			} `xml:"work_days,omitempty"`
+		} `xml:"sales_info,omitempty"`
 		Developer struct {
-			ID    string `xml:"id"`
-			Name  string `xml:"name"`
-			Phone string `xml:"phone"`
-			Site  string `xml:"site"`
-			Logo  string `xml:"logo"`
-		} `xml:"developer"`
-	} `xml:"complex"`
+			ID    string `xml:"id,omitempty"`
+			Name  string `xml:"name,omitempty"`
+			Phone string `xml:"phone,omitempty"`
+			Site  string `xml:"site,omitempty"`
+			Logo  string `xml:"logo,omitempty"`
+		} `xml:"developer,omitempty"`
+
+		Unknown     []UnknownElement `xml:",any"`
+		UnknownAttr []xml.Attr       `xml:",any,attr"`
+	} `xml:"complex,omitempty"`
+
+	Unknown     []UnknownElement `xml:",any"`
+	UnknownAttr []xml.Attr       `xml:",any,attr"`
+	Strict      bool             `xml:"-"` // Check also reports unknown elements and attributes
+}
+
+type DomclickBuilding struct {
+	ID            string `xml:"id,omitempty"`
+	Fz214         string `xml:"fz_214,omitempty"`
+	Name          string `xml:"name,omitempty"`
+	Floors        int64  `xml:"floors,omitempty"`
+	BuildingState string `xml:"building_state,omitempty"`
+	BuiltYear     int64  `xml:"built_year,omitempty"`
+	ReadyQuarter  int64  `xml:"ready_quarter,omitempty"`
+	BuildingType  string `xml:"building_type,omitempty"`
+	Image         string `xml:"image,omitempty"`
+	Flats         struct {
+		Flat []Flat `xml:"flat,omitempty"`
+	} `xml:"flats,omitempty"`
+
+	Unknown     []UnknownElement `xml:",any"`
+	UnknownAttr []xml.Attr       `xml:",any,attr"`
 }
 
 type Flat struct {
-	FlatID      string  `xml:"flat_id"`
-	Apartment   string  `xml:"apartment"`
-	Floor       int64   `xml:"floor"`
-	Room        *int64  `xml:"room"`
-	Plan        string  `xml:"plan"`
-	Balcony     string  `xml:"balcony"`
-	Renovation  string  `xml:"renovation"`
-	Price       float32 `xml:"price"`
-	Area        float32 `xml:"area"`
-	LivingArea  float32 `xml:"living_area"`
-	KitchenArea float32 `xml:"kitchen_area"`
+	FlatID      string  `xml:"flat_id,omitempty"`
+	Apartment   string  `xml:"apartment,omitempty"`
+	Floor       int64   `xml:"floor,omitempty"`
+	Room        *int64  `xml:"room,omitempty"`
+	Plan        string  `xml:"plan,omitempty"`
+	Balcony     string  `xml:"balcony,omitempty"`
+	Renovation  string  `xml:"renovation,omitempty"`
+	Price       float32 `xml:"price,omitempty"`
+	Area        float32 `xml:"area,omitempty"`
+	LivingArea  float32 `xml:"living_area,omitempty"`
+	KitchenArea float32 `xml:"kitchen_area,omitempty"`
 	RoomsArea   struct {
-		Area []string `xml:"area"`
-	} `xml:"rooms_area"`
-	Bathroom     string `xml:"bathroom"`
-	HousingType  string `xml:"housing_type"`
-	Decoration   int64  `xml:"decoration"`
-	ReadyHousing string `xml:"ready_housing"`
+		Area []string `xml:"area,omitempty"`
+	} `xml:"rooms_area,omitempty"`
+	Bathroom     string `xml:"bathroom,omitempty"`
+	HousingType  string `xml:"housing_type,omitempty"`
+	Decoration   int64  `xml:"decoration,omitempty"`
+	ReadyHousing string `xml:"ready_housing,omitempty"`
+
+	Unknown     []UnknownElement `xml:",any"`
+	UnknownAttr []xml.Attr       `xml:",any,attr"`
 }
 
+// Get downloads the feed with DefaultFetcher.
 func (f *DomclickFeed) Get(url string) (err error) {
-	resp, err := GetResponse(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	_, err = DefaultFetcher.FetchFeed(f, url)
+	return err
+}
 
-	err = statusCodeHandler(resp)
-	if err != nil {
-		return err
-	}
+// Marshal returns the feed as Domclick XML.
+func (f *DomclickFeed) Marshal() ([]byte, error) {
+	return marshalFeed(f, map[string]bool{"text": true})
+}
 
-	AttributeLastModified := resp.Header.Get("Last-Modified")
-	if AttributeLastModified != "" {
-		lastModifiedDate, err := time.Parse(time.RFC1123, resp.Header.Get("Last-Modified"))
-		if err != nil {
-			return err
+// WriteTo writes the feed as Domclick XML to w.
+func (f *DomclickFeed) WriteTo(w io.Writer) (n int64, err error) {
+	return writeFeed(w, f, map[string]bool{"text": true})
+}
+
+// Check runs the enabled Domclick rules of DefaultRules.
+func (f *DomclickFeed) Check() (results []string) {
+	return DefaultRules.Check(f)
+}
+
+// CheckWith is Check with options, e.g. checking the feed as of another date.
+func (f *DomclickFeed) CheckWith(options CheckOptions) (results []string) {
+	return DefaultRules.CheckWith(f, options)
+}
+
+var domclickRules = []Rule{
+	gateRule(FeedRule("domclick/feed-size", func(f *DomclickFeed, results *Results) {
+		if len(f.Complex.Buildings.Building) < 2 {
+			results.Add("", emptyFeed)
+		}
+	})),
+	freshnessRule[*DomclickFeed](PlatformDomclick),
+	FeedRule("domclick/complex", func(f *DomclickFeed, results *Results) {
+		path := "Complex"
+		checkString(path, "ID", f.Complex.ID, results)
+		checkString(path, "Name", f.Complex.Name, results)
+		checkString(path, "Address", f.Complex.Address, results)
+		checkString(path, "Latitude", f.Complex.Latitude, results)
+		checkString(path, "Longitude", f.Complex.Longitude, results)
+
+		for idx, image := range f.Complex.Images.Image {
+			checkStringWithPos(idx, "Complex.Images.Image", "Image", image, results)
+		}
+
+		path = "Complex.DescriptionMain"
+		checkString(path, "Title", f.Complex.DescriptionMain.Title, results)
+		checkString(path, "Text", f.Complex.DescriptionMain.Text, results)
+
+		for idx, profit := range f.Complex.ProfitsMain.ProfitMain {
+			path := "Complex.ProfitsMain.ProfitMain"
+			checkStringWithPos(idx, path, "Title", profit.Title, results)
+			checkStringWithPos(idx, path, "Text", profit.Text, results)
+			checkStringWithPos(idx, path, "Image", profit.Image, results)
+		}
+	}),
+	FeedRule("domclick/buildings", func(f *DomclickFeed, results *Results) {
+		for pos, building := range f.Complex.Buildings.Building {
+			path := "Complex.Buildings.Building"
+			checkStringWithPos(pos, path, "ID", building.ID, results)
+			checkStringWithID(building.ID, path, "Fz214", building.Fz214, results)
+			checkStringWithID(building.ID, path, "Name", building.Name, results)
+			checkZeroWithID(building.ID, path, "Floors", int(building.Floors), results)
+			checkStringWithID(building.ID, path, "BuildingState", building.BuildingState, results)
+			checkZeroWithID(building.ID, path, "BuiltYear", int(building.BuiltYear), results)
+			checkZeroWithID(building.ID, path, "ReadyQuarter", int(building.ReadyQuarter), results)
+			checkStringWithID(building.ID, path, "BuildingType", building.BuildingType, results)
+			checkEnumWithID(building.ID, path, "BuildingType", building.BuildingType, domclickBuildingTypeEnum, results)
+		}
+	}),
+	FeedRuleAt("domclick/deadline", func(f *DomclickFeed, now time.Time, results *Results) {
+		path := "Complex.Buildings.Building"
+		for _, building := range f.Complex.Buildings.Building {
+			checkQuarterWithID(building.ID, path, "ReadyQuarter", building.ReadyQuarter, results)
+			checkDeadlineYearWithID(building.ID, path, "BuiltYear", building.BuiltYear, now.Year(), results)
+
+			passed, ok := deadlinePassed(building.BuiltYear, building.ReadyQuarter, now.Year(), quarterOf(int(now.Month())))
+			if ok && building.BuildingState == "unfinished" {
+				results.Add("Complex.Buildings.Building.BuildingState", fmt.Sprintf("BuildingState == unfinished for %v. InternalID: %v", passed, building.ID))
+			}
+		}
+	}),
+	// Flats are checked against the floors of their building.
+	lotRule("domclick/flats", func(lot Flat, run *checkRun, results *Results) {
+		if building, idx := run.feed.(*DomclickFeed).flatAt(results.lot); building != nil {
+			checkDomclickFlat(lot, idx, int(building.Floors), results)
+		}
+	}),
+	FeedRule("domclick/sales-info", func(f *DomclickFeed, results *Results) {
+		path := "Complex.SalesInfo"
+		checkString(path, "SalesPhone", f.Complex.SalesInfo.SalesPhone, results)
+		checkString(path, "SalesAddress", f.Complex.SalesInfo.SalesAddress, results)
+		checkString(path, "SalesLatitude", f.Complex.SalesInfo.SalesLatitude, results)
+		checkString(path, "SalesLongitude", f.Complex.SalesInfo.SalesLongitude, results)
+	}),
+	FeedRule("domclick/developer", func(f *DomclickFeed, results *Results) {
+		path := "Complex.Developer"
+		checkString(path, "Name", f.Complex.Developer.Name, results)
+		checkString(path, "Phone", f.Complex.Developer.Phone, results)
+		checkString(path, "Site", f.Complex.Developer.Site, results)
+		checkString(path, "Logo", f.Complex.Developer.Logo, results)
+	}),
+	feedRule("domclick/descriptions", func(f *DomclickFeed, run *checkRun, results *Results) {
+		if strings.TrimSpace(f.Complex.DescriptionMain.Text) != "" {
+			checkDescription(run.options.descriptionRule(PlatformDomclick), f.Complex.ID, -1, "Complex.DescriptionMain", "Text", f.Complex.DescriptionMain.Text, results)
+		}
+	}),
+	FeedRule("domclick/unknown", func(f *DomclickFeed, results *Results) {
+		if f.Strict {
+			f.checkUnknown(results)
+		}
+	}),
+}
+
+// CheckUnknown reports elements and attributes of the complex, its buildings and flats that are not modelled by DomclickFeed.
+func (f *DomclickFeed) CheckUnknown() (results []string) {
+	unknown := &Results{lot: -1}
+	f.checkUnknown(unknown)
+	return unknown.messages()
+}
+
+func (f *DomclickFeed) checkUnknown(results *Results) {
+	checkUnknownWithID("", -1, "complexes", f.Unknown, f.UnknownAttr, results)
+	checkUnknownWithID(f.Complex.ID, -1, "Complex", f.Complex.Unknown, f.Complex.UnknownAttr, results)
+	position := 0
+	for _, building := range f.Complex.Buildings.Building {
+		checkUnknownWithID(building.ID, -1, "Complex.Buildings.Building", building.Unknown, building.UnknownAttr, results)
+		for _, lot := range building.Flats.Flat {
+			checkUnknownWithID(lot.FlatID, position, "Flats.Flat", lot.Unknown, lot.UnknownAttr, results)
+			position++
 		}
-		f.LastModified = lastModifiedDate
-	} else {
-		log.Println("Header not contains `Last-Modified`")
 	}
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
+}
+
+// flatAt returns the building of the flat at position, counted over the flats of all buildings, and the index
+// of the flat in the building.
+func (f *DomclickFeed) flatAt(position int) (building *DomclickBuilding, idx int) {
+	for i := range f.Complex.Buildings.Building {
+		building = &f.Complex.Buildings.Building[i]
+		if position < len(building.Flats.Flat) {
+			return building, position
+		}
+		position -= len(building.Flats.Flat)
 	}
-	err = xml.Unmarshal(responseBody, &f)
-	if err != nil {
-		return err
+	return nil, -1
+}
+
+// DiscardUnknown drops the unmodelled elements and attributes, so Marshal writes only known fields.
+func (f *DomclickFeed) DiscardUnknown() {
+	f.Unknown, f.UnknownAttr = nil, nil
+	f.Complex.Unknown, f.Complex.UnknownAttr = nil, nil
+	for i := range f.Complex.Buildings.Building {
+		building := &f.Complex.Buildings.Building[i]
+		building.Unknown, building.UnknownAttr = nil, nil
+		for j := range building.Flats.Flat {
+			building.Flats.Flat[j].Unknown, building.Flats.Flat[j].UnknownAttr = nil, nil
+		}
 	}
-	//TODO Исправить значение f.LastModified
-	return nil
 }
 
-func (f *DomclickFeed) Check() (results []string) {
-	if len(f.Complex.Buildings.Building) < 2 {
-		results = append(results, emptyFeed)
-		return results
+func checkDomclickFlat(lot Flat, idx int, floors int, results *Results) {
+	path := "Flats.Flat"
+	checkStringWithPos(idx, path, "FlatID", lot.FlatID, results)
+	checkZeroWithID(lot.FlatID, path, "Floor", int(lot.Floor), results)
+	if lot.Room == nil {
+		results.Add("Flats.Flat.Room", fmt.Sprintf("Field Flats.Room is empty. InternalID: %v", lot.FlatID))
 	}
-	path := "Complex"
-	checkString(path, "ID", f.Complex.ID, &results)
-	checkString(path, "Name", f.Complex.Name, &results)
-	checkString(path, "Address", f.Complex.Address, &results)
-	checkString(path, "Latitude", f.Complex.Latitude, &results)
-	checkString(path, "Longitude", f.Complex.Longitude, &results)
-
-	for idx, image := range f.Complex.Images.Image {
-		checkStringWithPos(idx, "Complex.Images.Image", "Image", image, &results)
+	checkStringWithID(lot.FlatID, path, "Plan", lot.Plan, results)
+	checkStringWithID(lot.FlatID, path, "Balcony", lot.Balcony, results)
+	checkEnumWithID(lot.FlatID, path, "Renovation", lot.Renovation, domclickRenovationEnum, results)
+	checkZeroWithID(lot.FlatID, path, "Price", lot.Price, results)
+	checkZeroWithID(lot.FlatID, path, "Area", lot.Area, results)
+	isOk := checkZeroWithID(lot.FlatID, path, "LivingArea", lot.LivingArea, results)
+	if !isOk {
+		for i, room := range lot.RoomsArea.Area {
+			if room == "" {
+				results.Add("Flats.Flat.RoomsArea.Area[]", fmt.Sprintf("Field Flats.Flat.RoomsArea.Area[%v] is empty. InternalID: %v", i, lot.FlatID))
+			}
+		}
 	}
 
-	path = "Complex.DescriptionMain"
-	checkString(path, "Title", f.Complex.DescriptionMain.Title, &results)
-	checkString(path, "Text", f.Complex.DescriptionMain.Text, &results)
+	checkZeroWithID(lot.FlatID, path, "KitchenArea", lot.KitchenArea, results)
+	checkStringWithID(lot.FlatID, path, "Bathroom", lot.Bathroom, results)
 
-	for idx, profit := range f.Complex.ProfitsMain.ProfitMain {
-		path := "Complex.ProfitsMain.ProfitMain"
-		checkStringWithPos(idx, path, "Title", profit.Title, &results)
-		checkStringWithPos(idx, path, "Text", profit.Text, &results)
-		checkStringWithPos(idx, path, "Image", profit.Image, &results)
+	if lot.Floor > int64(floors) {
+		results.Add("Flats.Flat.Floor", fmt.Sprintf("Field Flats.Flat.Floor is bigger than building.Floors. InternalID: %v", lot.FlatID))
 	}
+}
 
-	for pos, building := range f.Complex.Buildings.Building {
-		path := "Complex.Buildings.Building"
-		checkStringWithPos(pos, path, "ID", building.ID, &results)
-		checkStringWithID(building.ID, path, "Fz214", building.Fz214, &results)
-		checkStringWithID(building.ID, path, "Name", building.Name, &results)
-		checkZeroWithID(building.ID, path, "Floors", int(building.Floors), &results)
-		checkStringWithID(building.ID, path, "BuildingState", building.BuildingState, &results)
-		checkZeroWithID(building.ID, path, "BuiltYear", int(building.BuiltYear), &results)
-		checkZeroWithID(building.ID, path, "ReadyQuarter", int(building.ReadyQuarter), &results)
-		checkStringWithID(building.ID, path, "BuildingType", building.BuildingType, &results)
+// domclickRenovation is the value of Flats.Flat.Renovation.
+type domclickRenovation string
 
-		if building.BuiltYear < int64(time.Now().Year()) && building.BuildingState == "unfinished" {
-			results = append(results, fmt.Sprintf("BuildingState == unfinished for %v. InternalID: %v", building.BuiltYear, building.ID))
-		}
+const (
+	domclickRenovationNone    domclickRenovation = "без отделки"
+	domclickRenovationRough   domclickRenovation = "черновая"
+	domclickRenovationPreFine domclickRenovation = "предчистовая"
+	domclickRenovationFine    domclickRenovation = "чистовая"
+	domclickRenovationTurnkey domclickRenovation = "под ключ"
+)
 
-		f.checkLots(building.Flats.Flat, int(building.Floors), &results)
-	}
+// domclickBuildingType is the value of Complex.Buildings.Building.BuildingType.
+type domclickBuildingType string
 
-	path = "Complex.SalesInfo"
-	checkString(path, "SalesPhone", f.Complex.SalesInfo.SalesPhone, &results)
-	checkString(path, "SalesAddress", f.Complex.SalesInfo.SalesAddress, &results)
-	checkString(path, "SalesLatitude", f.Complex.SalesInfo.SalesLatitude, &results)
-	checkString(path, "SalesLongitude", f.Complex.SalesInfo.SalesLongitude, &results)
+const (
+	domclickBuildingTypeBrick         domclickBuildingType = "кирпичный"
+	domclickBuildingTypeMonolith      domclickBuildingType = "монолитный"
+	domclickBuildingTypeMonolithBrick domclickBuildingType = "монолитно-кирпичный"
+	domclickBuildingTypePanel         domclickBuildingType = "панельный"
+	domclickBuildingTypeBlock         domclickBuildingType = "блочный"
+	domclickBuildingTypeWood          domclickBuildingType = "деревянный"
+)
 
-	path = "Complex.Developer"
-	checkString(path, "Name", f.Complex.Developer.Name, &results)
-	checkString(path, "Phone", f.Complex.Developer.Phone, &results)
-	checkString(path, "Site", f.Complex.Developer.Site, &results)
-	checkString(path, "Logo", f.Complex.Developer.Logo, &results)
+var (
+	domclickRenovationEnum   = Enum{FoldCase: true, Values: enumValues(domclickRenovations.values()...)}
+	domclickBuildingTypeEnum = Enum{FoldCase: true, Values: enumValues(domclickBuildingTypes.values()...)}
+)
 
-	return results
+var domclickRenovations = enumTable[domclickRenovation, Renovation]{
+	{domclickRenovationNone, RenovationNone},
+	{domclickRenovationRough, RenovationRough},
+	{domclickRenovationPreFine, RenovationPreFine},
+	{domclickRenovationFine, RenovationFine},
+	{domclickRenovationTurnkey, RenovationTurnkey},
+}
+
+var domclickBalconies = enumTable[string, Balcony]{
+	{"нет", BalconyNone},
+	{"балкон", BalconyBalcony},
+	{"лоджия", BalconyLoggia},
+	{"балкон и лоджия", BalconyBoth},
+}
+
+var domclickBuildingTypes = enumTable[domclickBuildingType, BuildingMaterial]{
+	{domclickBuildingTypeBrick, BuildingMaterialBrick},
+	{domclickBuildingTypeMonolith, BuildingMaterialMonolith},
+	{domclickBuildingTypeMonolithBrick, BuildingMaterialMonolithBrick},
+	{domclickBuildingTypePanel, BuildingMaterialPanel},
+	{domclickBuildingTypeBlock, BuildingMaterialBlock},
+	{domclickBuildingTypeWood, BuildingMaterialWood},
 }
 
-func (f *DomclickFeed) checkLots(flats []Flat, floors int, results *[]string) {
-	for idx, lot := range flats {
-		path := "Flats.Flat"
-		checkStringWithPos(idx, path, "FlatID", lot.FlatID, results)
-		checkZeroWithID(lot.FlatID, path, "Floor", int(lot.Floor), results)
-		if lot.Room == nil {
-			*results = append(*results, fmt.Sprintf("Field Flats.Room is empty. InternalID: %v", lot.FlatID))
-		}
-		checkStringWithID(lot.FlatID, path, "Plan", lot.Plan, results)
-		checkStringWithID(lot.FlatID, path, "Balcony", lot.Balcony, results)
-		checkZeroWithID(lot.FlatID, path, "Price", lot.Price, results)
-		checkZeroWithID(lot.FlatID, path, "Area", lot.Area, results)
-		isOk := checkZeroWithID(lot.FlatID, path, "LivingArea", lot.LivingArea, results)
-		if !isOk {
-			for i, room := range lot.RoomsArea.Area {
-				if room == "" {
-					*results = append(*results, fmt.Sprintf("Field Flats.Flat.RoomsArea.Area[%v] is empty. InternalID: %v", i, lot.FlatID))
-				}
+// Lots returns the flats of all buildings of the complex as canonical lots.
+func (f *DomclickFeed) Lots() (lots []Lot, results []string) {
+	for _, building := range f.Complex.Buildings.Building {
+		material := mapFromPlatform(domclickBuildingTypes, building.ID, "Complex.Buildings.Building.BuildingType", building.BuildingType, &results)
+		for _, flat := range building.Flats.Flat {
+			lot := Lot{
+				Source:       PlatformDomclick,
+				ID:           flat.FlatID,
+				Category:     LotCategoryFlat,
+				Deal:         DealTypeSale,
+				NewBuilding:  true,
+				Description:  f.Complex.DescriptionMain.Text,
+				Phone:        f.Complex.SalesInfo.SalesPhone,
+				Address:      f.Complex.Address,
+				Latitude:     parseFloat(f.Complex.Latitude),
+				Longitude:    parseFloat(f.Complex.Longitude),
+				Price:        float64(flat.Price),
+				Currency:     "RUB",
+				Area:         float64(flat.Area),
+				LivingArea:   float64(flat.LivingArea),
+				KitchenArea:  float64(flat.KitchenArea),
+				Floor:        int(flat.Floor),
+				FloorsTotal:  int(building.Floors),
+				Material:     material,
+				ComplexID:    f.Complex.ID,
+				ComplexName:  f.Complex.Name,
+				BuildingID:   building.ID,
+				BuildingName: building.Name,
+				FlatNumber:   flat.Apartment,
+				BuiltYear:    int(building.BuiltYear),
+				ReadyQuarter: int(building.ReadyQuarter),
+				Finished:     building.BuildingState == "hand-over",
+				Images:       append([]string{}, f.Complex.Images.Image...),
+			}
+			if flat.Room != nil {
+				lot.Rooms = int(*flat.Room)
+				lot.Studio = *flat.Room == 0
+			}
+			if flat.Plan != "" {
+				lot.PlanImages = []string{flat.Plan}
 			}
+			lot.Renovation = mapFromPlatform(domclickRenovations, flat.FlatID, "Flats.Flat.Renovation", flat.Renovation, &results)
+			lot.Balcony = mapFromPlatform(domclickBalconies, flat.FlatID, "Flats.Flat.Balcony", flat.Balcony, &results)
+			lots = append(lots, lot)
 		}
+	}
+	return lots, results
+}
+
+// FromLots replaces the complex with the given lots. Domclick feeds describe a single complex of new-build
+// flats for sale, so other lots are reported and skipped. Complex level fields are taken from the first lot.
+func (f *DomclickFeed) FromLots(lots []Lot) (results []string) {
+	f.Complex.Buildings.Building = nil
+	if len(lots) == 0 {
+		return results
+	}
 
-		checkZeroWithID(lot.FlatID, path, "KitchenArea", lot.KitchenArea, results)
-		checkStringWithID(lot.FlatID, path, "Bathroom", lot.Bathroom, results)
+	first := lots[0]
+	f.Complex.ID = first.ComplexID
+	f.Complex.Name = first.ComplexName
+	f.Complex.Address = first.Address
+	f.Complex.Latitude = formatFloat(first.Latitude)
+	f.Complex.Longitude = formatFloat(first.Longitude)
+	f.Complex.DescriptionMain.Title = first.ComplexName
+	f.Complex.DescriptionMain.Text = first.Description
+	f.Complex.SalesInfo.SalesPhone = first.Phone
+	f.Complex.Images.Image = append([]string{}, first.Images...)
 
-		if lot.Floor > int64(floors) {
-			*results = append(*results, fmt.Sprintf("Field Flats.Flat.Floor is bigger than building.Floors. InternalID: %v", lot.FlatID))
+	buildings := make(map[string]int)
+	for _, lot := range lots {
+		if lot.Category != LotCategoryFlat || lot.Deal != DealTypeSale {
+			results = append(results, fmt.Sprintf("field Category value '%v %v' can not be mapped to %s. InternalID: %v", lot.Category, lot.Deal, PlatformDomclick, lot.ID))
+			continue
 		}
+		if lot.ComplexName != first.ComplexName || lot.ComplexID != first.ComplexID {
+			results = append(results, fmt.Sprintf("field ComplexName value '%v' differs from the feed complex '%v' and can not be mapped to %s. InternalID: %v", lot.ComplexName, first.ComplexName, PlatformDomclick, lot.ID))
+			continue
+		}
+
+		key := lot.BuildingID
+		if key == "" {
+			key = lot.BuildingName
+		}
+		idx, ok := buildings[key]
+		if !ok {
+			building := DomclickBuilding{
+				ID:           lot.BuildingID,
+				Name:         lot.BuildingName,
+				Floors:       int64(lot.FloorsTotal),
+				BuiltYear:    int64(lot.BuiltYear),
+				ReadyQuarter: int64(lot.ReadyQuarter),
+			}
+			if building.ID == "" {
+				building.ID = lot.BuildingName
+			}
+			building.BuildingState = "unfinished"
+			if lot.Finished {
+				building.BuildingState = "hand-over"
+			}
+			building.BuildingType = mapToPlatform(domclickBuildingTypes, PlatformDomclick, lot.ID, "Material", lot.Material, &results)
+			f.Complex.Buildings.Building = append(f.Complex.Buildings.Building, building)
+			idx = len(f.Complex.Buildings.Building) - 1
+			buildings[key] = idx
+		}
+
+		flat := Flat{
+			FlatID:      lot.ID,
+			Apartment:   lot.FlatNumber,
+			Floor:       int64(lot.Floor),
+			Price:       float32(lot.Price),
+			Area:        float32(lot.Area),
+			LivingArea:  float32(lot.LivingArea),
+			KitchenArea: float32(lot.KitchenArea),
+		}
+		if lot.Studio || lot.Rooms > 0 {
+			rooms := int64(lot.Rooms)
+			if lot.Studio {
+				rooms = 0
+			}
+			flat.Room = &rooms
+		}
+		if len(lot.PlanImages) > 0 {
+			flat.Plan = lot.PlanImages[0]
+		}
+		flat.Renovation = mapToPlatform(domclickRenovations, PlatformDomclick, lot.ID, "Renovation", lot.Renovation, &results)
+		flat.Balcony = mapToPlatform(domclickBalconies, PlatformDomclick, lot.ID, "Balcony", lot.Balcony, &results)
+
+		building := &f.Complex.Buildings.Building[idx]
+		building.Flats.Flat = append(building.Flats.Flat, flat)
 	}
+	return results
 }
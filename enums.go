@@ -0,0 +1,120 @@
+package price_placements_feeds
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Enum lists the values a platform accepts for a categorical field.
+type Enum struct {
+	Values []string
+	// FoldCase accepts the values in any letter case.
+	FoldCase bool
+}
+
+// enumValues converts the typed values of a platform enum for an Enum.
+func enumValues[V ~string](values ...V) []string {
+	strs := make([]string, len(values))
+	for idx, value := range values {
+		strs[idx] = string(value)
+	}
+	return strs
+}
+
+// Contains reports whether value is one of the accepted values.
+func (e Enum) Contains(value string) bool {
+	for _, v := range e.Values {
+		if v == value || e.FoldCase && strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Suggest returns the accepted value closest to a rejected one: a value differing only in letter case,
+// Cyrillic/Latin look-alike characters or surrounding spaces, or within a small edit distance.
+func (e Enum) Suggest(value string) (suggestion string, ok bool) {
+	skeleton := enumSkeleton(value)
+	best := -1
+	for _, v := range e.Values {
+		candidate := enumSkeleton(v)
+		if candidate == skeleton {
+			return v, true
+		}
+		distance := editDistance(skeleton, candidate)
+		if distance <= 2 && distance*3 <= len([]rune(candidate)) && (best < 0 || distance < best) {
+			suggestion, best = v, distance
+		}
+	}
+	return suggestion, best >= 0
+}
+
+// enumHomoglyphs maps lower-case Cyrillic letters to the Latin letters they look like.
+var enumHomoglyphs = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h',
+	'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x',
+}
+
+// enumSkeleton folds case, spaces and look-alike characters, so values that look the same compare equal.
+func enumSkeleton(value string) string {
+	var b strings.Builder
+	for _, r := range strings.Join(strings.Fields(value), " ") {
+		r = unicode.ToLower(r)
+		if latin, ok := enumHomoglyphs[r]; ok {
+			r = latin
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// hasMixedScripts reports whether value contains both Cyrillic and Latin letters.
+func hasMixedScripts(value string) bool {
+	var cyrillic, latin bool
+	for _, r := range value {
+		cyrillic = cyrillic || unicode.Is(unicode.Cyrillic, r)
+		latin = latin || unicode.Is(unicode.Latin, r)
+	}
+	return cyrillic && latin
+}
+
+// editDistance is the Levenshtein distance between a and b in runes.
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// enumMessage describes a value rejected by an enum, with a suggestion when there is one.
+func enumMessage(ID string, path string, fieldName string, value string, enum Enum) string {
+	suggestion, ok := enum.Suggest(value)
+	switch {
+	case !ok:
+		return fmt.Sprintf("field %s.%s has unknown value '%s'. InternalID: %s", path, fieldName, value, ID)
+	case hasMixedScripts(value):
+		return fmt.Sprintf("field %s.%s has unknown value '%s' mixing Cyrillic and Latin letters, did you mean '%s'? InternalID: %s", path, fieldName, value, suggestion, ID)
+	}
+	return fmt.Sprintf("field %s.%s has unknown value '%s', did you mean '%s'? InternalID: %s", path, fieldName, value, suggestion, ID)
+}
@@ -0,0 +1,96 @@
+package price_placements_feeds
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestEnumContains(t *testing.T) {
+	if !avitoDecorationEnum.Contains(string(avitoDecorationFine)) || avitoDecorationEnum.Contains("чистовая") {
+		t.Error("case-sensitive enum")
+	}
+	if !domclickRenovationEnum.Contains("Под Ключ") || domclickRenovationEnum.Contains("под  ключ") {
+		t.Error("case-insensitive enum")
+	}
+}
+
+func TestEnumSuggest(t *testing.T) {
+	tests := []struct {
+		enum  Enum
+		value string
+		want  string
+		ok    bool
+	}{
+		{cianDecorationEnum, "Fine", "fine", true},
+		{cianDecorationEnum, " prefine ", "preFine", true},
+		// "Без отдeлки" has a Latin e.
+		{avitoDecorationEnum, "Без отдeлки", "Без отделки", true},
+		{avitoHouseTypeEnum, "Кирпичны", "Кирпичный", true},
+		{realtyBuildingStateEnum, "handover", "hand-over", true},
+		{cianQuarterEnum, "fifth", "", false},
+		{avitoOperationTypeEnum, "Куплю", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.enum.Suggest(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Suggest(%q) = %q, %v, want %q, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestEnumMessage(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Без отдeлки", "field Ad.Decoration has unknown value 'Без отдeлки' mixing Cyrillic and Latin letters, did you mean 'Без отделки'? InternalID: 1"},
+		{"Чистовя", "field Ad.Decoration has unknown value 'Чистовя', did you mean 'Чистовая'? InternalID: 1"},
+		{"Дизайнерская", "field Ad.Decoration has unknown value 'Дизайнерская'. InternalID: 1"},
+	}
+	for _, tt := range tests {
+		if got := enumMessage("1", "Ad", "Decoration", tt.value, avitoDecorationEnum); got != tt.want {
+			t.Errorf("enumMessage(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEnumsDerivedFromTables(t *testing.T) {
+	tests := []struct {
+		name string
+		enum Enum
+		want []string
+	}{
+		{"avito category", avitoCategoryEnum, []string{"Квартиры", "Комнаты", "Дома, дачи, коттеджи", "Земельные участки", "Гаражи и машиноместа", "Коммерческая недвижимость"}},
+		{"avito operation type", avitoOperationTypeEnum, []string{"Продам", "Сдам"}},
+		{"cian decoration", cianDecorationEnum, []string{"without", "rough", "preFine", "fine"}},
+		{"cian quarter", cianQuarterEnum, []string{"first", "second", "third", "fourth"}},
+		{"domclick building type", domclickBuildingTypeEnum, enumValues(domclickBuildingTypes.values()...)},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.enum.Values, tt.want) {
+			t.Errorf("%s enum = %q, want %q", tt.name, tt.enum.Values, tt.want)
+		}
+	}
+
+	// Every value the feeds are converted to passes their validation.
+	for _, entry := range realtyRenovations {
+		if !realtyRenovationEnum.Contains(string(entry.Value)) {
+			t.Errorf("realty renovation '%s' is not accepted", entry.Value)
+		}
+	}
+}
+
+func TestCianQuarters(t *testing.T) {
+	for quarter := 1; quarter <= 4; quarter++ {
+		var results []string
+		number := strconv.Itoa(quarter)
+		value := mapToPlatform(cianQuarters, PlatformCian, "1", "ReadyQuarter", number, &results)
+		if !cianQuarterEnum.Contains(value) || len(results) != 0 {
+			t.Errorf("quarter %d = '%s', %q", quarter, value, results)
+		}
+		if back := mapFromPlatform(cianQuarters, "1", "Quarter", value, &results); back != number {
+			t.Errorf("quarter '%s' = %s", value, back)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package price_placements_feeds
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// FetchError is returned when a feed can't be downloaded, e.g. the host is down or the request timed out.
+type FetchError struct {
+	URL string
+	Err error
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("can't get feed %s: %v", e.URL, e.Err)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the request timed out.
+func (e *FetchError) Timeout() bool {
+	var netErr net.Error
+	return errors.As(e.Err, &netErr) && netErr.Timeout()
+}
+
+// HTTPStatusError is returned when the server answers with a status other than 200 OK.
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	// RetryAfter is the delay asked for by a Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("feed %s is not available: %s", e.URL, e.Status)
+}
+
+// DecodeError is returned when a feed is not valid XML or doesn't match the platform format.
+// Line and Offset point at the place where decoding stopped.
+type DecodeError struct {
+	URL    string
+	Line   int
+	Offset int64
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("can't decode feed %s at line %d (offset %d): %v", e.URL, e.Line, e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// EmptyFeedError is returned when the server answers with an empty body.
+type EmptyFeedError struct {
+	URL string
+}
+
+func (e *EmptyFeedError) Error() string {
+	return fmt.Sprintf("feed %s is empty", e.URL)
+}
+
+// LimitError is returned when a feed exceeds one of the Limits.
+type LimitError struct {
+	URL string
+	// Limit is the exceeded limit: "body size", "element depth", "lot count" or "parse timeout".
+	Limit string
+	// Max is the value of the limit, in bytes, elements, lots or nanoseconds.
+	Max int64
+}
+
+func (e *LimitError) Error() string {
+	max := strconv.FormatInt(e.Max, 10)
+	switch e.Limit {
+	case limitBodySize:
+		max += " bytes"
+	case limitParseTimeout:
+		max = time.Duration(e.Max).String()
+	}
+	return fmt.Sprintf("feed %s exceeds the %s limit of %s", e.URL, e.Limit, max)
+}
+
+// ErrorKind classifies fetch and parse failures.
+type ErrorKind string
+
+const (
+	ErrorKindFetch      ErrorKind = "fetch"
+	ErrorKindHTTPStatus ErrorKind = "http_status"
+	ErrorKindDecode     ErrorKind = "decode"
+	ErrorKindEmpty      ErrorKind = "empty"
+	ErrorKindLimit      ErrorKind = "limit"
+	ErrorKindOther      ErrorKind = "other"
+)
+
+// ErrorKindOf returns the kind of a Get error, or an empty kind for nil.
+func ErrorKindOf(err error) ErrorKind {
+	var (
+		fetchErr  *FetchError
+		statusErr *HTTPStatusError
+		decodeErr *DecodeError
+		emptyErr  *EmptyFeedError
+		limitErr  *LimitError
+	)
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &fetchErr):
+		return ErrorKindFetch
+	case errors.As(err, &statusErr):
+		return ErrorKindHTTPStatus
+	case errors.As(err, &decodeErr):
+		return ErrorKindDecode
+	case errors.As(err, &emptyErr):
+		return ErrorKindEmpty
+	case errors.As(err, &limitErr):
+		return ErrorKindLimit
+	}
+	return ErrorKindOther
+}
@@ -0,0 +1,92 @@
+package price_placements_feeds
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorKindOf(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing.xml":
+			http.NotFound(w, r)
+		case "/empty.xml":
+		case "/broken.xml":
+			w.Write([]byte("<Ads>\n<Ad><Id>1</Id>\n</Ads>"))
+		case "/big.xml":
+			w.Write([]byte("<Ads>" + strings.Repeat("<Ad><Id>1</Id></Ad>", 100) + "</Ads>"))
+		}
+	}))
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	defer server.Close()
+
+	fetcher := &Fetcher{Retry: RetryPolicy{MaxAttempts: 1}, Limits: Limits{MaxBodySize: 1000}}
+	tests := []struct {
+		url  string
+		want ErrorKind
+	}{
+		{closed.URL + "/feed.xml", ErrorKindFetch},
+		{server.URL + "/missing.xml", ErrorKindHTTPStatus},
+		{server.URL + "/empty.xml", ErrorKindEmpty},
+		{server.URL + "/broken.xml", ErrorKindDecode},
+		{server.URL + "/big.xml", ErrorKindLimit},
+	}
+	for _, tt := range tests {
+		_, err := fetcher.Fetch(&AvitoFeed{}, tt.url)
+		if kind := ErrorKindOf(err); kind != tt.want {
+			t.Errorf("%s: error kind = %s, want %s (%v)", tt.url, kind, tt.want, err)
+		}
+		if kind := ErrorKindOf(fmt.Errorf("project 1: %w", err)); kind != tt.want {
+			t.Errorf("%s: wrapped error kind = %s, want %s", tt.url, kind, tt.want)
+		}
+	}
+
+	if kind := ErrorKindOf(nil); kind != "" {
+		t.Errorf("nil error kind = %s", kind)
+	}
+	if kind := ErrorKindOf(errors.New("no feeds")); kind != ErrorKindOther {
+		t.Errorf("other error kind = %s", kind)
+	}
+}
+
+func TestErrorMessages(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&HTTPStatusError{URL: "https://example.com/feed.xml", StatusCode: 404, Status: "404 Not Found"},
+			"feed https://example.com/feed.xml is not available: 404 Not Found"},
+		{&DecodeError{URL: "https://example.com/feed.xml", Line: 3, Offset: 42, Err: errors.New("unexpected EOF")},
+			"can't decode feed https://example.com/feed.xml at line 3 (offset 42): unexpected EOF"},
+		{&EmptyFeedError{URL: "https://example.com/feed.xml"}, "feed https://example.com/feed.xml is empty"},
+		{&LimitError{URL: "https://example.com/feed.xml", Limit: limitBodySize, Max: 1000},
+			"feed https://example.com/feed.xml exceeds the body size limit of 1000 bytes"},
+		{&LimitError{URL: "https://example.com/feed.xml", Limit: limitParseTimeout, Max: int64(time.Minute)},
+			"feed https://example.com/feed.xml exceeds the parse timeout limit of 1m0s"},
+		{&LimitError{URL: "https://example.com/feed.xml", Limit: limitLots, Max: 10},
+			"feed https://example.com/feed.xml exceeds the lot count limit of 10"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestDecodeErrorPosition(t *testing.T) {
+	var feed AvitoFeed
+	err := decodeFeed("feed.xml", []byte("<Ads>\n<Ad><Id>1</Id>\n</Ads>"), &feed, Limits{})
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("decodeFeed() = %v", err)
+	}
+	if decodeErr.Line != 3 || decodeErr.Offset == 0 || decodeErr.URL != "feed.xml" {
+		t.Errorf("error = %+v", decodeErr)
+	}
+}
@@ -0,0 +1,66 @@
+package price_placements_feeds
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Feed is implemented by AvitoFeed, CianFeed, DomclickFeed and RealtyFeed.
+type Feed interface {
+	Get(url string) error
+	Check() []string
+	CheckWith(options CheckOptions) []string
+	Marshal() ([]byte, error)
+	WriteTo(w io.Writer) (n int64, err error)
+	LotSource
+	LotTarget
+}
+
+// NewFeed returns an empty feed of the platform.
+func NewFeed(platform Platform) (Feed, error) {
+	switch platform {
+	case PlatformAvito:
+		return &AvitoFeed{}, nil
+	case PlatformCian:
+		return &CianFeed{}, nil
+	case PlatformDomclick:
+		return &DomclickFeed{}, nil
+	case PlatformRealty:
+		return &RealtyFeed{}, nil
+	}
+	return nil, fmt.Errorf("unknown platform: %s", platform)
+}
+
+// feedLastModified returns the LastModified value of a feed.
+func feedLastModified(feed Feed) time.Time {
+	switch f := feed.(type) {
+	case *AvitoFeed:
+		return f.LastModified
+	case *CianFeed:
+		return f.LastModified
+	case *DomclickFeed:
+		return f.LastModified
+	case *RealtyFeed:
+		return f.LastModified
+	}
+	return time.Time{}
+}
+
+// setFeedLastModified sets the LastModified value of a feed. Yandex Realty feeds without
+// a Last-Modified header take the generation date of the feed instead.
+func setFeedLastModified(feed Feed, lastModified time.Time) {
+	switch f := feed.(type) {
+	case *AvitoFeed:
+		f.LastModified = lastModified
+	case *CianFeed:
+		f.LastModified = lastModified
+	case *DomclickFeed:
+		f.LastModified = lastModified
+	case *RealtyFeed:
+		f.LastModified = lastModified
+		if lastModified.IsZero() {
+			f.LastModified, _ = f.inFeedDate()
+		}
+	}
+}
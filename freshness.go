@@ -0,0 +1,85 @@
+package price_placements_feeds
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultMaxFeedAge is the age after which a feed is reported as stale. It follows how often the platforms
+// load feeds: Cian several times a day, Avito and Yandex Realty daily, Domclick new building catalogs
+// every few days.
+var DefaultMaxFeedAge = map[Platform]time.Duration{
+	PlatformAvito:    24 * time.Hour,
+	PlatformCian:     12 * time.Hour,
+	PlatformDomclick: 72 * time.Hour,
+	PlatformRealty:   24 * time.Hour,
+}
+
+// feedClockSkew is the difference between the feed server clock and ours that is not reported.
+const feedClockSkew = 5 * time.Minute
+
+// FeedDate returns the date the feed was generated: the Last-Modified header, or the dates inside
+// the feed when the platform has them. Source is "header" or the name of the feed field.
+// Only Yandex Realty feeds have dates inside, generation-date and last-update-date: the Avito, Cian and
+// Domclick formats carry no generation date, so without the header their date is unknown.
+func FeedDate(feed Feed) (date time.Time, source string) {
+	if lastModified := feedLastModified(feed); !lastModified.IsZero() {
+		return lastModified, "header"
+	}
+	if f, ok := feed.(*RealtyFeed); ok {
+		return f.inFeedDate()
+	}
+	return time.Time{}, ""
+}
+
+// CheckFreshness reports a feed that is older than maxAge or dated in the future compared to now.
+// DefaultMaxFeedAge of the platform is used when maxAge is zero. A feed with an unknown date is not reported. The "<platform>/freshness" rules
+// run the same check with CheckOptions.
+func CheckFreshness(platform Platform, feed Feed, maxAge time.Duration, now time.Time) (results []string) {
+	freshness := &Results{lot: -1}
+	checkFreshness(platform, feed, maxAge, now, freshness)
+	return freshness.messages()
+}
+
+// freshnessRule makes the freshness rule of the platform whose feeds are of type F.
+func freshnessRule[F Feed](platform Platform) Rule {
+	return feedRule(string(platform)+"/freshness", func(feed F, run *checkRun, results *Results) {
+		checkFreshness(platform, feed, run.options.MaxAge[platform], run.now, results)
+	})
+}
+
+func checkFreshness(platform Platform, feed Feed, maxAge time.Duration, now time.Time, results *Results) {
+	if maxAge <= 0 {
+		maxAge = DefaultMaxFeedAge[platform]
+	}
+
+	date, source := FeedDate(feed)
+	if date.IsZero() {
+		return
+	}
+
+	age := now.Sub(date)
+	switch {
+	case age < -feedClockSkew:
+		results.Add("", fmt.Sprintf("feed is dated in the future: %s (%s)", date.Format(time.RFC3339), source))
+	case maxAge > 0 && age > maxAge:
+		results.Add("", fmt.Sprintf("feed is stale: %s (%s) is older than %s", date.Format(time.RFC3339), source, maxAge))
+	}
+}
+
+// inFeedDate returns generation-date, or the latest last-update-date of the offers.
+func (f *RealtyFeed) inFeedDate() (date time.Time, source string) {
+	if generated, err := time.Parse(time.RFC3339Nano, f.GenerationDate); err == nil {
+		return generated, "generation-date"
+	}
+	for _, offer := range f.Offer {
+		updated, err := time.Parse(time.RFC3339Nano, offer.LastUpdateDate)
+		if err == nil && updated.After(date) {
+			date = updated
+		}
+	}
+	if date.IsZero() {
+		return date, ""
+	}
+	return date, "last-update-date"
+}
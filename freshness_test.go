@@ -0,0 +1,86 @@
+package price_placements_feeds
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFreshnessRule(t *testing.T) {
+	tests := []struct {
+		name         string
+		lastModified time.Time
+		maxAge       time.Duration
+		want         []string
+	}{
+		{"fresh", testNow.Add(-time.Hour), 0, nil},
+		{"stale", testNow.Add(-48 * time.Hour), 0, []string{"feed is stale: 2024-05-13T12:00:00Z (header) is older than 24h0m0s"}},
+		{"max age", testNow.Add(-48 * time.Hour), 72 * time.Hour, nil},
+		{"clock skew", testNow.Add(time.Minute), 0, nil},
+		{"future", testNow.Add(time.Hour), 0, []string{"feed is dated in the future: 2024-05-15T13:00:00Z (header)"}},
+		{"unknown", time.Time{}, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := testAvitoFeed(12)
+			feed.LastModified = tt.lastModified
+			results := DefaultRules.Only("avito/freshness").CheckWith(feed, CheckOptions{Now: testClock, MaxAge: map[Platform]time.Duration{PlatformAvito: tt.maxAge}})
+			if !reflect.DeepEqual(results, tt.want) {
+				t.Errorf("CheckWith() = %q, want %q", results, tt.want)
+			}
+		})
+	}
+}
+
+func TestRealtyFreshnessUsesGenerationDate(t *testing.T) {
+	feed := &RealtyFeed{GenerationDate: "2024-05-10T09:00:00+03:00"}
+	want := []string{"feed is stale: 2024-05-10T09:00:00+03:00 (generation-date) is older than 24h0m0s"}
+	if results := CheckFreshness(PlatformRealty, feed, 0, testNow); !reflect.DeepEqual(results, want) {
+		t.Errorf("CheckFreshness() = %q, want %q", results, want)
+	}
+	if results := CheckFreshness(PlatformRealty, feed, 10*24*time.Hour, testNow); results != nil {
+		t.Errorf("CheckFreshness() with a longer max age = %q", results)
+	}
+}
+
+func TestDefaultMaxFeedAge(t *testing.T) {
+	date := testNow.Add(-18 * time.Hour)
+	tests := []struct {
+		platform Platform
+		feed     Feed
+		stale    bool
+	}{
+		{PlatformAvito, &AvitoFeed{LastModified: date}, false},
+		{PlatformCian, &CianFeed{LastModified: date}, true},
+		{PlatformDomclick, &DomclickFeed{LastModified: date}, false},
+		{PlatformRealty, &RealtyFeed{LastModified: date}, false},
+	}
+	for _, tt := range tests {
+		if results := CheckFreshness(tt.platform, tt.feed, 0, testNow); (len(results) != 0) != tt.stale {
+			t.Errorf("%s feed of 18h: %q", tt.platform, results)
+		}
+	}
+}
+
+func TestFeedDateWithoutHeader(t *testing.T) {
+	for _, feed := range []Feed{&AvitoFeed{}, &CianFeed{}, &DomclickFeed{}} {
+		if date, source := FeedDate(feed); !date.IsZero() || source != "" {
+			t.Errorf("FeedDate(%T) = %s, %q", feed, date, source)
+		}
+	}
+
+	realty := &RealtyFeed{Offer: []Offer{
+		{LastUpdateDate: "2024-05-14T10:00:00+03:00"},
+		{LastUpdateDate: "2024-05-15T10:00:00+03:00"},
+		{LastUpdateDate: "yesterday"},
+	}}
+	date, source := FeedDate(realty)
+	if want := time.Date(2024, time.May, 15, 7, 0, 0, 0, time.UTC); !date.Equal(want) || source != "last-update-date" {
+		t.Errorf("FeedDate() = %s, %q", date, source)
+	}
+
+	realty.LastModified = testNow
+	if date, source := FeedDate(realty); !date.Equal(testNow) || source != "header" {
+		t.Errorf("FeedDate() with a header = %s, %q", date, source)
+	}
+}
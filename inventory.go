@@ -0,0 +1,315 @@
+package price_placements_feeds
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ColumnMapping maps Lot field names (e.g. "ID", "Price", "Images") to inventory column headers.
+// Headers are matched case-insensitively and fields without a column are left empty.
+// Enum fields take canonical values ("flat", "sale", "fine"), list fields take URLs separated
+// by spaces, commas or semicolons.
+type ColumnMapping map[string]string
+
+// DefaultColumnMapping expects the column headers to be the Lot field names.
+func DefaultColumnMapping() ColumnMapping {
+	mapping := make(ColumnMapping)
+	for _, field := range lotFields {
+		mapping[field] = field
+	}
+	return mapping
+}
+
+var lotFields = []string{
+	"ID", "Category", "Deal", "NewBuilding", "Apartments", "Created",
+	"Description", "Phone", "Address", "Latitude", "Longitude", "Price", "Currency",
+	"Rooms", "Studio", "OpenPlan", "Area", "LivingArea", "KitchenArea", "CeilingHeight",
+	"Floor", "FloorsTotal", "Renovation", "Balcony", "Material",
+	"ComplexID", "ComplexName", "BuildingID", "BuildingName", "Section", "FlatNumber",
+	"BuiltYear", "ReadyQuarter", "Finished", "Images", "PlanImages", "FloorPlanImages",
+}
+
+// ReadInventoryCSV reads lots from a CSV price list. The delimiter (comma or semicolon) is
+// detected from the header line. Rows with invalid values are kept and reported in results.
+func ReadInventoryCSV(r io.Reader, mapping ColumnMapping) (lots []Lot, results []string, err error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, nil, err
+	}
+	if i := bytes.IndexByte(header, '\n'); i >= 0 {
+		header = header[:i]
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	var rows []inventoryRow
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, inventoryRow{number: line, values: values})
+	}
+	lots, results = rowsToLots(rows, mapping)
+	return lots, results, nil
+}
+
+// ReadInventoryXLSX reads lots from the first sheet of an XLSX price list.
+func ReadInventoryXLSX(r io.ReaderAt, size int64, mapping ColumnMapping) (lots []Lot, results []string, err error) {
+	rows, err := readXLSXRows(r, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	lots, results = rowsToLots(rows, mapping)
+	return lots, results, nil
+}
+
+// BuildFeed builds a feed of the platform from lots, checks it and writes it to w.
+// The results contain the conversion report followed by the feed check results.
+func BuildFeed(platform Platform, lots []Lot, w io.Writer) (results []string, err error) {
+	return BuildFeedWith(platform, lots, w, CheckOptions{})
+}
+
+// BuildFeedWith is BuildFeed with check options. The feed is generated and checked as of the time of the options.
+func BuildFeedWith(platform Platform, lots []Lot, w io.Writer, options CheckOptions) (results []string, err error) {
+	feed, err := NewFeed(platform)
+	if err != nil {
+		return nil, err
+	}
+
+	generatedAt := options.now()
+	options.Now = func() time.Time { return generatedAt }
+	setFeedLastModified(feed, generatedAt)
+	results = feed.FromLots(lots)
+	results = append(results, feed.CheckWith(options)...)
+
+	_, err = feed.WriteTo(w)
+	return results, err
+}
+
+// inventoryRow is a row of a price list with its number in the file, the one a spreadsheet shows.
+type inventoryRow struct {
+	number int
+	values []string
+}
+
+func rowsToLots(rows []inventoryRow, mapping ColumnMapping) (lots []Lot, results []string) {
+	if len(rows) == 0 {
+		return nil, []string{"inventory is empty"}
+	}
+
+	columns := make(map[string]int)
+	for idx, name := range rows[0].values {
+		columns[normalizeHeader(name)] = idx
+	}
+
+	fields := make(map[string]int)
+	for _, field := range lotFields {
+		header, ok := mapping[field]
+		if !ok {
+			continue
+		}
+		if idx, ok := columns[normalizeHeader(header)]; ok {
+			fields[field] = idx
+		}
+	}
+	if _, ok := fields["ID"]; !ok {
+		results = append(results, fmt.Sprintf("column '%s' for field ID is not found", mapping["ID"]))
+	}
+	var unknown []string
+	for field := range mapping {
+		if !isLotField(field) {
+			unknown = append(unknown, field)
+		}
+	}
+	sort.Strings(unknown)
+	for _, field := range unknown {
+		results = append(results, fmt.Sprintf("field %s is not a Lot field", field))
+	}
+
+	for _, row := range rows[1:] {
+		if isEmptyRow(row.values) {
+			continue
+		}
+		var lot Lot
+		for _, field := range lotFields {
+			idx, ok := fields[field]
+			if !ok || idx >= len(row.values) {
+				continue
+			}
+			value := strings.TrimSpace(row.values[idx])
+			if value == "" {
+				continue
+			}
+			if err := setLotField(&lot, field, value); err != nil {
+				results = append(results, fmt.Sprintf("row %d: field %s: %v", row.number, field, err))
+			}
+		}
+		lots = append(lots, lot)
+	}
+	return lots, results
+}
+
+func normalizeHeader(s string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(s, "\ufeff")))
+}
+
+func isLotField(field string) bool {
+	for _, f := range lotFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func setLotField(lot *Lot, field string, value string) (err error) {
+	switch field {
+	case "ID":
+		lot.ID = value
+	case "Category":
+		lot.Category = LotCategory(strings.ToLower(value))
+	case "Deal":
+		lot.Deal = DealType(strings.ToLower(value))
+	case "NewBuilding":
+		lot.NewBuilding = isYes(value)
+	case "Apartments":
+		lot.Apartments = isYes(value)
+	case "Created":
+		lot.Created, err = parseInventoryDate(value)
+	case "Description":
+		lot.Description = value
+	case "Phone":
+		lot.Phone = value
+	case "Address":
+		lot.Address = value
+	case "Latitude":
+		lot.Latitude, err = parseInventoryFloat(value)
+	case "Longitude":
+		lot.Longitude, err = parseInventoryFloat(value)
+	case "Price":
+		lot.Price, err = parseInventoryFloat(value)
+	case "Currency":
+		lot.Currency = value
+	case "Rooms":
+		lot.Rooms, err = parseInventoryInt(value)
+	case "Studio":
+		lot.Studio = isYes(value)
+	case "OpenPlan":
+		lot.OpenPlan = isYes(value)
+	case "Area":
+		lot.Area, err = parseInventoryFloat(value)
+	case "LivingArea":
+		lot.LivingArea, err = parseInventoryFloat(value)
+	case "KitchenArea":
+		lot.KitchenArea, err = parseInventoryFloat(value)
+	case "CeilingHeight":
+		lot.CeilingHeight, err = parseInventoryFloat(value)
+	case "Floor":
+		lot.Floor, err = parseInventoryInt(value)
+	case "FloorsTotal":
+		lot.FloorsTotal, err = parseInventoryInt(value)
+	case "Renovation":
+		lot.Renovation = Renovation(strings.ToLower(value))
+	case "Balcony":
+		lot.Balcony = Balcony(strings.ToLower(value))
+	case "Material":
+		lot.Material = BuildingMaterial(strings.ToLower(value))
+	case "ComplexID":
+		lot.ComplexID = value
+	case "ComplexName":
+		lot.ComplexName = value
+	case "BuildingID":
+		lot.BuildingID = value
+	case "BuildingName":
+		lot.BuildingName = value
+	case "Section":
+		lot.Section = value
+	case "FlatNumber":
+		lot.FlatNumber = value
+	case "BuiltYear":
+		lot.BuiltYear, err = parseInventoryInt(value)
+	case "ReadyQuarter":
+		lot.ReadyQuarter, err = parseInventoryInt(value)
+	case "Finished":
+		lot.Finished = isYes(value)
+	case "Images":
+		lot.Images = splitInventoryList(value)
+	case "PlanImages":
+		lot.PlanImages = splitInventoryList(value)
+	case "FloorPlanImages":
+		lot.FloorPlanImages = splitInventoryList(value)
+	}
+	return err
+}
+
+func parseInventoryFloat(value string) (float64, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, value)
+	f, err := strconv.ParseFloat(strings.ReplaceAll(cleaned, ",", "."), 64)
+	if err != nil {
+		return 0, fmt.Errorf("value '%s' is not a number", value)
+	}
+	return f, nil
+}
+
+func parseInventoryInt(value string) (int, error) {
+	f, err := parseInventoryFloat(value)
+	if err != nil {
+		return 0, err
+	}
+	if f != float64(int(f)) {
+		return 0, fmt.Errorf("value '%s' is not an integer", value)
+	}
+	return int(f), nil
+}
+
+func parseInventoryDate(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02", "02.01.2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	// XLSX stores dates as the number of days since 1899-12-30.
+	if days, err := strconv.ParseFloat(value, 64); err == nil && days > 0 {
+		return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).Add(time.Duration(days * 24 * float64(time.Hour))), nil
+	}
+	return time.Time{}, fmt.Errorf("value '%s' is not a date", value)
+}
+
+func splitInventoryList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || unicode.IsSpace(r)
+	})
+}
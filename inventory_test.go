@@ -0,0 +1,155 @@
+package price_placements_feeds
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testInventoryCSV = "ID;Price;Rooms;Description;Images\n" +
+	"lot-1;5 000 000;2;\"Двухкомнатная квартира\nс видом на парк\";https://example.com/1.jpg, https://example.com/2.jpg\n" +
+	"\n" +
+	";;;;\n" +
+	"lot-2;много;1,5;Однокомнатная;\n"
+
+func TestReadInventoryCSV(t *testing.T) {
+	lots, results, err := ReadInventoryCSV(strings.NewReader(testInventoryCSV), DefaultColumnMapping())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lots) != 2 {
+		t.Fatalf("lots = %d, want 2", len(lots))
+	}
+	if lots[0].ID != "lot-1" || lots[0].Price != 5000000 || lots[0].Rooms != 2 {
+		t.Errorf("lot = %+v", lots[0])
+	}
+	if lots[0].Description != "Двухкомнатная квартира\nс видом на парк" {
+		t.Errorf("Description = %q", lots[0].Description)
+	}
+	if want := []string{"https://example.com/1.jpg", "https://example.com/2.jpg"}; !reflect.DeepEqual(lots[0].Images, want) {
+		t.Errorf("Images = %v, want %v", lots[0].Images, want)
+	}
+
+	// The quoted description spans lines 2 and 3, so the second lot is on line 6 of the file.
+	want := []string{
+		"row 6: field Price: value 'много' is not a number",
+		"row 6: field Rooms: value '1,5' is not an integer",
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %q, want %q", results, want)
+	}
+}
+
+func TestReadInventoryCSVCommaDelimiter(t *testing.T) {
+	data := "id,price\nlot-1,100\n"
+	mapping := ColumnMapping{"ID": "ID", "Price": "PRICE"}
+	lots, results, err := ReadInventoryCSV(strings.NewReader(data), mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 || len(lots) != 1 || lots[0].ID != "lot-1" || lots[0].Price != 100 {
+		t.Errorf("lots = %+v, results = %q", lots, results)
+	}
+}
+
+func TestReadInventoryCSVUnknownFields(t *testing.T) {
+	mapping := ColumnMapping{"ID": "ID", "Zone": "zone", "Cost": "cost", "Agent": "agent", "Price": "price"}
+	want := []string{
+		"field Agent is not a Lot field",
+		"field Cost is not a Lot field",
+		"field Zone is not a Lot field",
+	}
+	for i := 0; i < 10; i++ {
+		_, results, err := ReadInventoryCSV(strings.NewReader("id;price\nlot-1;100\n"), mapping)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(results, want) {
+			t.Fatalf("results = %q, want %q", results, want)
+		}
+	}
+}
+
+// testXLSX builds an XLSX file with the sheet XML and shared strings.
+func testXLSX(t *testing.T, sheetData string, shared ...string) []byte {
+	t.Helper()
+
+	var sharedXML strings.Builder
+	for _, s := range shared {
+		sharedXML.WriteString("<si><t>" + s + "</t></si>")
+	}
+	parts := []struct{ name, data string }{
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Price" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`},
+		{"xl/sharedStrings.xml", `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + sharedXML.String() + `</sst>`},
+		{"xl/worksheets/sheet1.xml", `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			sheetData + `</sheetData></worksheet>`},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, part := range parts {
+		w, err := archive.Create(part.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(part.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadInventoryXLSX(t *testing.T) {
+	// Row 3 has no cells and is left out of the sheet, as spreadsheet applications do.
+	data := testXLSX(t,
+		`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="D1" t="s"><v>2</v></c></row>`+
+			`<row r="2"><c r="A2" t="inlineStr"><is><t>lot-1</t></is></c><c r="B2"><v>5000000</v></c><c r="D2" t="b"><v>1</v></c></row>`+
+			`<row r="4"><c r="A4" t="inlineStr"><is><t>lot-2</t></is></c><c r="B4" t="s"><v>3</v></c></row>`,
+		"ID", "Price", "Studio", "по запросу")
+
+	lots, results, err := ReadInventoryXLSX(bytes.NewReader(data), int64(len(data)), DefaultColumnMapping())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lots) != 2 {
+		t.Fatalf("lots = %d, want 2", len(lots))
+	}
+	if lots[0].ID != "lot-1" || lots[0].Price != 5000000 || !lots[0].Studio {
+		t.Errorf("lot = %+v", lots[0])
+	}
+	if lots[1].ID != "lot-2" || lots[1].Studio {
+		t.Errorf("lot = %+v", lots[1])
+	}
+	if want := []string{"row 4: field Price: value 'по запросу' is not a number"}; !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %q, want %q", results, want)
+	}
+}
+
+func TestReadInventoryXLSXInvalidReference(t *testing.T) {
+	for _, ref := range []string{"a1", "1A", "A", "AB1C", "ABCD1"} {
+		data := testXLSX(t, `<row r="1"><c r="`+ref+`"><v>1</v></c></row>`)
+		_, _, err := ReadInventoryXLSX(bytes.NewReader(data), int64(len(data)), DefaultColumnMapping())
+		if err == nil || !strings.Contains(err.Error(), "invalid cell reference '"+ref+"'") {
+			t.Errorf("ref %s: err = %v", ref, err)
+		}
+	}
+}
+
+func TestXLSXColumnIndex(t *testing.T) {
+	tests := map[string]int{"A1": 0, "Z10": 25, "AA3": 26, "AB12": 27, "XFD1048576": 16383}
+	for ref, want := range tests {
+		got, err := xlsxColumnIndex(ref)
+		if err != nil || got != want {
+			t.Errorf("xlsxColumnIndex(%s) = %d, %v, want %d", ref, got, err, want)
+		}
+	}
+}
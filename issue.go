@@ -0,0 +1,125 @@
+package price_placements_feeds
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Issue is a check result with the rule that reported it and the lot it concerns.
+type Issue struct {
+	// Rule is the name of the rule that reported the issue, e.g. "avito/required".
+	Rule string `json:"rule"`
+	// Field is the checked field with list indexes replaced by [], e.g. "object.Phones.PhoneSchema[].Number".
+	Field string `json:"field,omitempty"`
+	// LotID is the identifier of the lot. It is empty for feed issues and lots without an identifier.
+	LotID string `json:"lot_id,omitempty"`
+	// Position is the index of the lot in the feed. It is nil for feed issues.
+	Position *int   `json:"position,omitempty"`
+	Message  string `json:"message"`
+}
+
+// IsFeedIssue reports whether the issue concerns the whole feed rather than a lot.
+func (i Issue) IsFeedIssue() bool {
+	return i.LotID == "" && i.Position == nil
+}
+
+// String returns the message of the issue.
+func (i Issue) String() string {
+	return i.Message
+}
+
+// Lot returns the lot identifier, or "#N" for a lot known only by its position.
+func (i Issue) Lot() string {
+	if i.LotID == "" && i.Position != nil {
+		return "#" + strconv.Itoa(*i.Position)
+	}
+	return i.LotID
+}
+
+// issueFieldIndex matches the list indexes replaced by [] in Issue.Field.
+var issueFieldIndex = regexp.MustCompile(`\[(\d+)\]`)
+
+// IssueSummary aggregates the issues sharing a rule and field.
+type IssueSummary struct {
+	Rule  string `json:"rule"`
+	Field string `json:"field,omitempty"`
+	// Count is the number of issues and Lots the number of distinct lots they concern.
+	Count int `json:"count"`
+	Lots  int `json:"lots"`
+	// LotPercent is Lots as a percentage of all lots of the feed.
+	LotPercent float64 `json:"lot_percent"`
+	// Examples are up to a handful of affected lots.
+	Examples []string `json:"examples,omitempty"`
+	// Message is the first issue of the group.
+	Message string `json:"message"`
+}
+
+// String describes the group, e.g. "Ad.Decoration (avito/required) in 92% of lots (2760 lots, e.g. 1, 2, 3)".
+// A group of feed issues is described by its first message.
+func (s IssueSummary) String() string {
+	if s.Lots == 0 {
+		if s.Count == 1 {
+			return s.Message
+		}
+		return fmt.Sprintf("%s (%d times)", s.Message, s.Count)
+	}
+
+	text := s.Rule
+	if s.Field != "" {
+		text = fmt.Sprintf("%s (%s)", s.Field, s.Rule)
+	}
+	if s.LotPercent > 0 {
+		text += fmt.Sprintf(" in %s%% of lots", strconv.FormatFloat(s.LotPercent, 'f', -1, 64))
+	}
+	text += fmt.Sprintf(" (%d lots", s.Lots)
+	if len(s.Examples) > 0 {
+		text += ", e.g. " + strings.Join(s.Examples, ", ")
+	}
+	return text + ")"
+}
+
+// SummarizeIssues groups issues by rule and field, the groups affecting most lots first.
+// lotCount is the number of lots in the feed, examples the number of example lots kept per group.
+func SummarizeIssues(issues []Issue, lotCount int, examples int) (summaries []IssueSummary) {
+	index := make(map[string]int)
+	lots := make(map[string]map[string]bool)
+	for _, issue := range issues {
+		key := issue.Rule + "\x00" + issue.Field
+		idx, ok := index[key]
+		if !ok {
+			idx = len(summaries)
+			index[key] = idx
+			lots[key] = make(map[string]bool)
+			summaries = append(summaries, IssueSummary{Rule: issue.Rule, Field: issue.Field, Message: issue.Message})
+		}
+		summary := &summaries[idx]
+		summary.Count++
+
+		lot := issue.Lot()
+		if lot == "" || lots[key][lot] {
+			continue
+		}
+		lots[key][lot] = true
+		summary.Lots++
+		if len(summary.Examples) < examples {
+			summary.Examples = append(summary.Examples, lot)
+		}
+	}
+
+	for i := range summaries {
+		if lotCount > 0 && summaries[i].Lots > 0 {
+			summaries[i].LotPercent = math.Round(float64(summaries[i].Lots)*1000/float64(lotCount)) / 10
+		}
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].Lots != summaries[j].Lots {
+			return summaries[i].Lots > summaries[j].Lots
+		}
+		return summaries[i].Count > summaries[j].Count
+	})
+	return summaries
+}
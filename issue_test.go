@@ -0,0 +1,47 @@
+package price_placements_feeds
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSummarizeIssues(t *testing.T) {
+	feed := testAvitoFeed(20)
+	for i := 0; i < 10; i++ {
+		feed.Ad[i].Price = 0
+	}
+	feed.Ad[3].ID = ""
+	issues := DefaultRules.Only("avito/ids", "avito/required").CheckIssues(feed, CheckOptions{})
+
+	summaries := SummarizeIssues(issues, len(feed.Ad), 3)
+	if len(summaries) != 2 {
+		t.Fatalf("summaries = %+v", summaries)
+	}
+	price := summaries[0]
+	if price.Rule != "avito/required" || price.Field != "Ad.Price" || price.Count != 10 || price.Lots != 10 || price.LotPercent != 50 {
+		t.Errorf("price summary = %+v", price)
+	}
+	if !reflect.DeepEqual(price.Examples, []string{"ad-0", "ad-1", "ad-2"}) {
+		t.Errorf("examples = %q", price.Examples)
+	}
+	if got, want := price.String(), "Ad.Price (avito/required) in 50% of lots (10 lots, e.g. ad-0, ad-1, ad-2)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if ids := summaries[1]; ids.Rule != "avito/ids" || ids.Field != "Ad[].ID" || !reflect.DeepEqual(ids.Examples, []string{"#3"}) {
+		t.Errorf("ids summary = %+v", ids)
+	}
+}
+
+func TestIssueSummaryOfFeedIssues(t *testing.T) {
+	issues := []Issue{
+		{Rule: "cian/building-consistency", Message: "buildings disagree"},
+		{Rule: "cian/building-consistency", Message: "buildings disagree again"},
+	}
+	summaries := SummarizeIssues(issues, 10, 3)
+	if len(summaries) != 1 || summaries[0].Lots != 0 {
+		t.Fatalf("summaries = %+v", summaries)
+	}
+	if got, want := summaries[0].String(), "buildings disagree (2 times)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,164 @@
+package price_placements_feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"time"
+)
+
+// Limits bound the resources spent on downloading and parsing one feed.
+// Zero fields take their defaults and negative ones disable the limit.
+type Limits struct {
+	// MaxBodySize is the size of the response body in bytes. Defaults to 256 MiB.
+	MaxBodySize int64 `json:"max_body_size"`
+	// MaxDepth is the nesting depth of XML elements. Defaults to 64.
+	MaxDepth int `json:"max_depth"`
+	// MaxLots is the number of lots in a feed. Defaults to 500000.
+	MaxLots int `json:"max_lots"`
+	// Timeout is the time to make the request and read the response body. Defaults to 5m.
+	Timeout Duration `json:"timeout"`
+	// ParseTimeout is the time to decode the XML. Defaults to 1m.
+	ParseTimeout Duration `json:"parse_timeout"`
+}
+
+const (
+	limitBodySize     = "body size"
+	limitDepth        = "element depth"
+	limitLots         = "lot count"
+	limitParseTimeout = "parse timeout"
+)
+
+func (l Limits) maxBodySize() int64 {
+	return limitValue(l.MaxBodySize, 256<<20)
+}
+
+func (l Limits) maxDepth() int64 {
+	return limitValue(int64(l.MaxDepth), 64)
+}
+
+func (l Limits) maxLots() int64 {
+	return limitValue(int64(l.MaxLots), 500000)
+}
+
+func (l Limits) timeout() time.Duration {
+	return time.Duration(limitValue(int64(l.Timeout.Duration), int64(5*time.Minute)))
+}
+
+func (l Limits) parseTimeout() time.Duration {
+	return time.Duration(limitValue(int64(l.ParseTimeout.Duration), int64(time.Minute)))
+}
+
+// limitValue returns value, or fallback when value is zero. Negative values mean no limit and are returned as 0.
+func limitValue(value int64, fallback int64) int64 {
+	switch {
+	case value < 0:
+		return 0
+	case value == 0:
+		return fallback
+	}
+	return value
+}
+
+// deadlineReader fails reads after the deadline, which stops an xml.Decoder reading from it.
+type deadlineReader struct {
+	reader   io.Reader
+	url      string
+	timeout  time.Duration
+	deadline time.Time
+}
+
+func (r *deadlineReader) Read(p []byte) (int, error) {
+	if !r.deadline.IsZero() && time.Now().After(r.deadline) {
+		return 0, &LimitError{URL: r.url, Limit: limitParseTimeout, Max: int64(r.timeout)}
+	}
+	return r.reader.Read(p)
+}
+
+// scanFeed scans the XML before it is decoded for elements nested deeper than maxDepth and for more than
+// maxLots lot elements named lotElement. Zero limits are not checked. Syntax errors are left to the decoding.
+func scanFeed(url string, reader io.Reader, maxDepth int64, lotElement string, maxLots int64) error {
+	decoder := xml.NewDecoder(reader)
+	var depth, lots int64
+	for {
+		token, err := decoder.RawToken()
+		if err != nil {
+			var limitErr *LimitError
+			if errors.As(err, &limitErr) {
+				return limitErr
+			}
+			return nil
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if maxDepth > 0 && depth > maxDepth {
+				return &LimitError{URL: url, Limit: limitDepth, Max: maxDepth}
+			}
+			if maxLots > 0 && t.Name.Local == lotElement {
+				lots++
+				if lots > maxLots {
+					return &LimitError{URL: url, Limit: limitLots, Max: maxLots}
+				}
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// lotElement returns the name of the lot elements of a feed: Avito Ad, Cian object, Yandex Realty offer
+// or Domclick flat. It is empty for other values.
+func lotElement(v any) string {
+	switch v.(type) {
+	case *AvitoFeed:
+		return "Ad"
+	case *CianFeed:
+		return "object"
+	case *RealtyFeed:
+		return "offer"
+	case *DomclickFeed:
+		return "flat"
+	}
+	return ""
+}
+
+// decodeFeed unmarshals feed XML into v, returning an EmptyFeedError for an empty body, a LimitError
+// when the XML is nested too deep, has too many lots or takes too long, and a DecodeError with the position of the failure for invalid XML.
+func decodeFeed(url string, data []byte, v any, limits Limits) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &EmptyFeedError{URL: url}
+	}
+	newReader := func() io.Reader {
+		return bytes.NewReader(data)
+	}
+	if timeout := limits.parseTimeout(); timeout > 0 {
+		deadline := time.Now().Add(timeout)
+		newReader = func() io.Reader {
+			return &deadlineReader{reader: bytes.NewReader(data), url: url, timeout: timeout, deadline: deadline}
+		}
+	}
+
+	maxDepth, maxLots := limits.maxDepth(), limits.maxLots()
+	element := lotElement(v)
+	if element == "" {
+		maxLots = 0
+	}
+	if maxDepth > 0 || maxLots > 0 {
+		if err := scanFeed(url, newReader(), maxDepth, element, maxLots); err != nil {
+			return err
+		}
+	}
+	decoder := xml.NewDecoder(newReader())
+	if err := decoder.Decode(v); err != nil {
+		var limitErr *LimitError
+		if errors.As(err, &limitErr) {
+			return limitErr
+		}
+		offset := decoder.InputOffset()
+		line := 1 + bytes.Count(data[:offset], []byte("\n"))
+		return &DecodeError{URL: url, Line: line, Offset: offset, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package price_placements_feeds
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeFeedLotLimit(t *testing.T) {
+	avito, err := xml.Marshal(testAvitoFeed(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	domclick, err := xml.Marshal(testDomclickFeed(2, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		feed    Feed
+		maxLots int
+		wantErr bool
+	}{
+		{"avito over", avito, &AvitoFeed{}, 2, true},
+		{"avito at limit", avito, &AvitoFeed{}, 3, false},
+		{"avito unlimited", avito, &AvitoFeed{}, -1, false},
+		{"domclick flats over", domclick, &DomclickFeed{}, 3, true},
+		{"domclick flats at limit", domclick, &DomclickFeed{}, 4, false},
+		{"cian", []byte(`<feed><object/><object/><object/></feed>`), &CianFeed{}, 2, true},
+		{"realty", []byte(`<realty-feed><offer/><offer/><offer/></realty-feed>`), &RealtyFeed{}, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := decodeFeed("feed.xml", tt.data, tt.feed, Limits{MaxLots: tt.maxLots})
+			var limitErr *LimitError
+			if got := errors.As(err, &limitErr); got != tt.wantErr {
+				t.Fatalf("decodeFeed() = %v, want limit error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if limitErr.Limit != limitLots || limitErr.Max != int64(tt.maxLots) {
+					t.Errorf("error = %+v", limitErr)
+				}
+				if lots := feedLots(tt.feed); len(lots) != 0 {
+					t.Errorf("feed over the limit is decoded: %d lots", len(lots))
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestDecodeFeedDepthLimit(t *testing.T) {
+	data := "<Ads><Ad>" + strings.Repeat("<a>", 10) + strings.Repeat("</a>", 10) + "</Ad></Ads>"
+	err := decodeFeed("feed.xml", []byte(data), &AvitoFeed{}, Limits{MaxDepth: 8})
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != limitDepth {
+		t.Errorf("decodeFeed() = %v", err)
+	}
+}
+
+func TestFetchFeedLotLimit(t *testing.T) {
+	data, err := xml.Marshal(testAvitoFeed(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	fetcher := &Fetcher{Limits: Limits{MaxLots: 10}}
+	_, err = fetcher.FetchFeed(&AvitoFeed{}, server.URL)
+	if ErrorKindOf(err) != ErrorKindLimit {
+		t.Errorf("FetchFeed() = %v", err)
+	}
+}
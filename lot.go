@@ -0,0 +1,244 @@
+package price_placements_feeds
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Platform string
+
+const (
+	PlatformAvito    Platform = "avito"
+	PlatformCian     Platform = "cian"
+	PlatformDomclick Platform = "domclick"
+	PlatformRealty   Platform = "realty"
+)
+
+type LotCategory string
+
+const (
+	LotCategoryFlat       LotCategory = "flat"
+	LotCategoryRoom       LotCategory = "room"
+	LotCategoryHouse      LotCategory = "house"
+	LotCategoryLand       LotCategory = "land"
+	LotCategoryGarage     LotCategory = "garage"
+	LotCategoryCommercial LotCategory = "commercial"
+	LotCategoryStorage    LotCategory = "storage"
+)
+
+type DealType string
+
+const (
+	DealTypeSale DealType = "sale"
+	DealTypeRent DealType = "rent"
+)
+
+type Renovation string
+
+const (
+	RenovationNone     Renovation = "none"
+	RenovationRough    Renovation = "rough"
+	RenovationPreFine  Renovation = "pre-fine"
+	RenovationFine     Renovation = "fine"
+	RenovationTurnkey  Renovation = "turnkey"
+	RenovationDesigner Renovation = "designer"
+)
+
+type Balcony string
+
+const (
+	BalconyNone    Balcony = "none"
+	BalconyBalcony Balcony = "balcony"
+	BalconyLoggia  Balcony = "loggia"
+	BalconyBoth    Balcony = "balcony-and-loggia"
+)
+
+type BuildingMaterial string
+
+const (
+	BuildingMaterialBrick         BuildingMaterial = "brick"
+	BuildingMaterialMonolith      BuildingMaterial = "monolith"
+	BuildingMaterialMonolithBrick BuildingMaterial = "monolith-brick"
+	BuildingMaterialPanel         BuildingMaterial = "panel"
+	BuildingMaterialBlock         BuildingMaterial = "block"
+	BuildingMaterialWood          BuildingMaterial = "wood"
+)
+
+// Lot is the platform independent description of a lot, used to convert one feed type into another.
+// ComplexID and BuildingID are catalogue identifiers of the Source platform and are only copied
+// into feeds of the same platform. Lots without a Source, e.g. read from an inventory, are copied as is.
+type Lot struct {
+	Source      Platform
+	ID          string
+	Category    LotCategory
+	Deal        DealType
+	NewBuilding bool
+	Apartments  bool
+	Created     time.Time
+
+	Description string
+	Phone       string
+	Address     string
+	Latitude    float64
+	Longitude   float64
+
+	Price    float64
+	Currency string
+
+	Rooms         int
+	Studio        bool
+	OpenPlan      bool
+	Area          float64
+	LivingArea    float64
+	KitchenArea   float64
+	CeilingHeight float64
+	Floor         int
+	FloorsTotal   int
+	Renovation    Renovation
+	Balcony       Balcony
+	Material      BuildingMaterial
+
+	ComplexID    string
+	ComplexName  string
+	BuildingID   string
+	BuildingName string
+	Section      string
+	FlatNumber   string
+	BuiltYear    int
+	ReadyQuarter int
+	Finished     bool
+
+	Images     []string
+	PlanImages []string
+	// FloorPlanImages show the lot on the plan of its floor.
+	FloorPlanImages []string
+}
+
+// LotSource is a feed that can be read as canonical lots.
+type LotSource interface {
+	Lots() (lots []Lot, results []string)
+}
+
+// LotTarget is a feed that can be built from canonical lots.
+type LotTarget interface {
+	FromLots(lots []Lot) (results []string)
+}
+
+// Convert fills dst with the lots of src. The results list values and fields that could not be mapped.
+func Convert(src LotSource, dst LotTarget) (results []string) {
+	lots, results := src.Lots()
+	results = append(results, dst.FromLots(lots)...)
+	return results
+}
+
+// enumTable maps platform values to canonical ones. The first entry of a canonical value
+// is the spelling written into feeds.
+type enumTable[V ~string, T ~string] []struct {
+	Value     V
+	Canonical T
+}
+
+func (t enumTable[V, T]) canonical(value string) (canonical T, ok bool) {
+	value = strings.TrimSpace(value)
+	for _, entry := range t {
+		if strings.EqualFold(string(entry.Value), value) {
+			return entry.Canonical, true
+		}
+	}
+	return canonical, false
+}
+
+func (t enumTable[V, T]) value(canonical T) (value V, ok bool) {
+	for _, entry := range t {
+		if entry.Canonical == canonical {
+			return entry.Value, true
+		}
+	}
+	return "", false
+}
+
+// values returns the platform values of the table, e.g. to validate them with an Enum.
+func (t enumTable[V, T]) values() []V {
+	values := make([]V, len(t))
+	for idx, entry := range t {
+		values[idx] = entry.Value
+	}
+	return values
+}
+
+// mapFromPlatform returns the canonical value of a platform enum and reports values missing in the table.
+func mapFromPlatform[V ~string, T ~string](table enumTable[V, T], ID string, fieldName string, value string, results *[]string) (canonical T) {
+	if value == "" {
+		return canonical
+	}
+	canonical, ok := table.canonical(value)
+	if !ok {
+		*results = append(*results, fmt.Sprintf("field %s value '%v' can not be mapped. InternalID: %v", fieldName, value, ID))
+	}
+	return canonical
+}
+
+// mapToPlatform returns the platform spelling of a canonical enum and reports values the platform does not support.
+func mapToPlatform[V ~string, T ~string](table enumTable[V, T], platform Platform, ID string, fieldName string, canonical T, results *[]string) (value string) {
+	if canonical == "" {
+		return ""
+	}
+	platformValue, ok := table.value(canonical)
+	if !ok {
+		*results = append(*results, fmt.Sprintf("field %s value '%v' can not be mapped to %s. InternalID: %v", fieldName, canonical, platform, ID))
+	}
+	return string(platformValue)
+}
+
+// copyCatalogID returns a catalogue identifier when the lot comes from the same platform or has no source,
+// and reports it otherwise.
+func copyCatalogID(lot Lot, platform Platform, fieldName string, value string, results *[]string) string {
+	if value == "" {
+		return ""
+	}
+	if lot.Source != "" && lot.Source != platform {
+		*results = append(*results, fmt.Sprintf("field %s can not be mapped from %s to %s. InternalID: %v", fieldName, lot.Source, platform, lot.ID))
+		return ""
+	}
+	return value
+}
+
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", "."), 64)
+	return f
+}
+
+func formatFloat(f float64) string {
+	if f == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func isYes(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "да", "true", "1", "yes", "+":
+		return true
+	}
+	return false
+}
+
+// splitPhone splits a Russian phone number into the country code and the ten-digit number.
+func splitPhone(phone string) (countryCode string, number string) {
+	var digits strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	d := digits.String()
+	if len(d) == 11 && (d[0] == '7' || d[0] == '8') {
+		return "+7", d[1:]
+	}
+	if len(d) == 10 {
+		return "+7", d
+	}
+	return "", d
+}
@@ -0,0 +1,221 @@
+package price_placements_feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+const realtyNamespace = "http://webmaster.yandex.ru/schemas/feed/realty/2010-06"
+
+// xmlNode is a minimal element tree used to post-process the output of xml.Marshal:
+// encoding/xml can neither omit empty nested structs nor write CDATA for plain string fields.
+// Character data is kept in text nodes, which have no name, in document order with the elements.
+type xmlNode struct {
+	name     xml.Name
+	attr     []xml.Attr
+	text     string
+	children []*xmlNode
+}
+
+// marshalFeed marshals a feed, drops empty elements and writes the text of cdata elements as CDATA.
+func marshalFeed(feed any, cdata map[string]bool) ([]byte, error) {
+	raw, err := xml.Marshal(feed)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := parseXMLTree(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if root != nil {
+		writeXMLNode(&buf, root, 0, "", cdata)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func writeFeed(w io.Writer, feed any, cdata map[string]bool) (n int64, err error) {
+	data, err := marshalFeed(feed, cdata)
+	if err != nil {
+		return 0, err
+	}
+	written, err := w.Write(data)
+	return int64(written), err
+}
+
+func parseXMLTree(data []byte) (root *xmlNode, err error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*xmlNode
+	for {
+		token, err := d.RawToken()
+		if err == io.EOF {
+			return root, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name, attr: restoreNamespaceDeclarations(t.Copy().Attr)}
+			if len(stack) == 0 {
+				root = node
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) == 0 {
+				continue
+			}
+			parent := stack[len(stack)-1]
+			if last := len(parent.children) - 1; last >= 0 && parent.children[last].isText() {
+				parent.children[last].text += string(t)
+			} else {
+				parent.children = append(parent.children, &xmlNode{text: string(t)})
+			}
+		}
+	}
+}
+
+// restoreNamespaceDeclarations undoes the prefix xml.Marshal invents for preserved
+// xmlns:prefix attributes, e.g. xmlns:_xmlns="xmlns" _xmlns:xsi="..." becomes xmlns:xsi="...".
+func restoreNamespaceDeclarations(attrs []xml.Attr) []xml.Attr {
+	prefixes := make(map[string]bool)
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" && attr.Value == "xmlns" {
+			prefixes[attr.Name.Local] = true
+		}
+	}
+	if len(prefixes) == 0 {
+		return attrs
+	}
+
+	restored := make([]xml.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		switch {
+		case attr.Name.Space == "xmlns" && prefixes[attr.Name.Local]:
+			continue
+		case prefixes[attr.Name.Space]:
+			attr.Name.Space = "xmlns"
+		}
+		restored = append(restored, attr)
+	}
+	return restored
+}
+
+func (n *xmlNode) isText() bool {
+	return n.name.Local == ""
+}
+
+func (n *xmlNode) isEmpty() bool {
+	if n.isText() {
+		return strings.TrimSpace(n.text) == ""
+	}
+	if len(n.attr) > 0 {
+		return false
+	}
+	for _, child := range n.children {
+		if !child.isEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+func xmlNodeName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+// writeXMLStart writes the start tag of n in the default namespace ns, leaving out a declaration of ns,
+// and returns the default namespace of its children.
+func writeXMLStart(buf *bytes.Buffer, n *xmlNode, ns string) string {
+	buf.WriteString("<" + xmlNodeName(n.name))
+	for _, attr := range n.attr {
+		if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			if attr.Value == ns {
+				continue
+			}
+			ns = attr.Value
+		}
+		buf.WriteString(" " + xmlNodeName(attr.Name) + `="`)
+		xml.EscapeText(buf, []byte(attr.Value))
+		buf.WriteString(`"`)
+	}
+	buf.WriteString(">")
+	return ns
+}
+
+// writeXMLNode writes n indented by depth. Elements with only text are written on one line. Elements with
+// text between their children are written as they are: xml.Marshal writes none, so they come from the
+// inner XML of unknown elements, where it may be mixed content or the indentation of the source.
+func writeXMLNode(buf *bytes.Buffer, n *xmlNode, depth int, ns string, cdata map[string]bool) {
+	indent := strings.Repeat("  ", depth)
+	buf.WriteString(indent)
+	ns = writeXMLStart(buf, n, ns)
+
+	var (
+		text     strings.Builder
+		children []*xmlNode
+	)
+	for _, child := range n.children {
+		switch {
+		case child.isText():
+			text.WriteString(child.text)
+		case !child.isEmpty():
+			children = append(children, child)
+		}
+	}
+
+	switch {
+	case len(children) == 0:
+		if cdata[n.name.Local] && text.Len() > 0 {
+			buf.WriteString("<![CDATA[" + strings.ReplaceAll(text.String(), "]]>", "]]]]><![CDATA[>") + "]]>")
+		} else {
+			xml.EscapeText(buf, []byte(text.String()))
+		}
+	case text.Len() > 0:
+		for _, child := range n.children {
+			writeXMLInline(buf, child, ns)
+		}
+	default:
+		buf.WriteByte('\n')
+		for _, child := range children {
+			writeXMLNode(buf, child, depth+1, ns, cdata)
+		}
+		buf.WriteString(indent)
+	}
+
+	buf.WriteString("</" + xmlNodeName(n.name) + ">")
+	if depth > 0 {
+		buf.WriteByte('\n')
+	}
+}
+
+// inlineTextEscaper escapes character data like xml.EscapeText but keeps line breaks and tabs as they are.
+var inlineTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+
+// writeXMLInline writes n and its children without changing their character data.
+func writeXMLInline(buf *bytes.Buffer, n *xmlNode, ns string) {
+	if n.isText() {
+		inlineTextEscaper.WriteString(buf, n.text)
+		return
+	}
+	ns = writeXMLStart(buf, n, ns)
+	for _, child := range n.children {
+		writeXMLInline(buf, child, ns)
+	}
+	buf.WriteString("</" + xmlNodeName(n.name) + ">")
+}
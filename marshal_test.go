@@ -0,0 +1,158 @@
+package price_placements_feeds
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var roundTripFeeds = []struct {
+	platform Platform
+	root     string
+	data     string
+}{
+	{PlatformAvito, "<Ads", `<?xml version="1.0" encoding="UTF-8"?>
+<Ads formatVersion="3" target="Avito.ru">
+  <Ad>
+    <Id>ad-1</Id>
+    <Description><![CDATA[<p>Квартира & парк</p>]]></Description>
+    <Price>5000000</Price>
+    <Promo kind="x2">2</Promo>
+  </Ad>
+  <Generator>crm</Generator>
+</Ads>`},
+	{PlatformCian, "<feed>", `<?xml version="1.0" encoding="UTF-8"?>
+<feed>
+  <feed_version>2</feed_version>
+  <object>
+    <ExternalId>obj-1</ExternalId>
+    <Description><![CDATA[<b>Квартира</b> & парк]]></Description>
+    <Promo>top</Promo>
+  </object>
+  <Generator>crm</Generator>
+</feed>`},
+	{PlatformRealty, "<realty-feed xmlns=", `<?xml version="1.0" encoding="UTF-8"?>
+<realty-feed xmlns="http://webmaster.yandex.ru/schemas/feed/realty/2010-06">
+  <generation-date>2024-05-15T12:00:00+03:00</generation-date>
+  <offer internal-id="offer-1">
+    <type>продажа</type>
+    <description><![CDATA[<p>Квартира & парк</p>]]></description>
+    <promo>top</promo>
+  </offer>
+  <generator>crm</generator>
+</realty-feed>`},
+	{PlatformDomclick, "<complexes>", `<?xml version="1.0" encoding="UTF-8"?>
+<complexes>
+  <complex>
+    <id>c1</id>
+    <name>ЖК Парк</name>
+    <buildings>
+      <building>
+        <id>b1</id>
+        <flats>
+          <flat>
+            <flat_id>f1</flat_id>
+            <promo>top</promo>
+          </flat>
+        </flats>
+      </building>
+    </buildings>
+  </complex>
+  <generator>crm</generator>
+</complexes>`},
+}
+
+func TestFeedRoundTrip(t *testing.T) {
+	for _, tt := range roundTripFeeds {
+		t.Run(string(tt.platform), func(t *testing.T) {
+			first, _ := NewFeed(tt.platform)
+			if err := xml.Unmarshal([]byte(tt.data), first); err != nil {
+				t.Fatal(err)
+			}
+			if lots := feedLots(first); len(lots) != 1 || lotID(lots[0]) == "" {
+				t.Fatalf("lots = %+v", lots)
+			}
+			data, err := first.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), tt.root) {
+				t.Errorf("marshalled feed has no %s root:\n%s", tt.root, data)
+			}
+			for _, kept := range []string{"promo", "Promo", "enerator"} {
+				if strings.Contains(tt.data, kept) && !strings.Contains(string(data), kept) {
+					t.Errorf("unknown element %s is lost:\n%s", kept, data)
+				}
+			}
+			if strings.Contains(tt.data, "CDATA") && !strings.Contains(string(data), "<![CDATA[") {
+				t.Errorf("description is not written as CDATA:\n%s", data)
+			}
+
+			second, _ := NewFeed(tt.platform)
+			if err := xml.Unmarshal(data, second); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(first, second) {
+				t.Errorf("feed changed in a round trip:\n%#v\n%#v", first, second)
+			}
+		})
+	}
+}
+
+func TestCianFeedAcceptsAnyRoot(t *testing.T) {
+	var feed CianFeed
+	if err := xml.Unmarshal([]byte(`<Feed><object><ExternalId>1</ExternalId></object></Feed>`), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Object) != 1 {
+		t.Errorf("objects = %+v", feed.Object)
+	}
+	data, err := feed.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<feed>") || feed.XMLName.Local != "Feed" {
+		t.Errorf("Marshal() = %s", data)
+	}
+}
+
+func TestUnknownElementRoundTrip(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<realty-feed xmlns="http://webmaster.yandex.ru/schemas/feed/realty/2010-06">
+  <offer internal-id="offer-1">
+    <type>продажа</type>
+    <crm:note xmlns:crm="urn:example:crm">Звонить <crm:b>после 18:00</crm:b> &amp; в выходные</crm:note>
+    <extra xmlns="urn:example:extra">
+      <source>crm</source>
+    </extra>
+  </offer>
+</realty-feed>`
+	var first RealtyFeed
+	if err := xml.Unmarshal([]byte(data), &first); err != nil {
+		t.Fatal(err)
+	}
+	out, err := first.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<crm:note xmlns:crm="urn:example:crm">Звонить <crm:b>после 18:00</crm:b> &amp; в выходные</crm:note>`,
+		"<extra xmlns=\"urn:example:extra\">\n      <source>crm</source>\n    </extra>",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Marshal() has no %s:\n%s", want, out)
+		}
+	}
+	if strings.Count(string(out), `xmlns="http://webmaster.yandex.ru/schemas/feed/realty/2010-06"`) != 1 {
+		t.Errorf("feed namespace is repeated:\n%s", out)
+	}
+
+	var second RealtyFeed
+	if err := xml.Unmarshal(out, &second); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(first.Offer[0].Unknown, second.Offer[0].Unknown) {
+		t.Errorf("unknown elements changed in a round trip:\n%+v\n%+v", first.Offer[0].Unknown, second.Offer[0].Unknown)
+	}
+}
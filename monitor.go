@@ -0,0 +1,465 @@
+package price_placements_feeds
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Duration is a time.Duration that is read from JSON as a string like "30m" or "24h".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// MonitorConfig lists the monitored projects. It is usually read from a JSON file with LoadMonitorConfig.
+type MonitorConfig struct {
+	Projects []Project `json:"projects"`
+	// Workers is the number of feeds fetched at the same time. Defaults to 4.
+	Workers int `json:"workers"`
+	// PerHost is the number of feeds fetched from the same host at the same time. Defaults to 1.
+	PerHost int `json:"per_host"`
+	// Interval is used for feeds without their own interval. Defaults to 1h.
+	Interval Duration `json:"interval"`
+	// StaleAfter is the age of LastModified after which a feed is reported as stale. Zero disables the check.
+	StaleAfter Duration `json:"stale_after"`
+	// MaxAge is the feed age, per platform, after which a freshness issue is added to the result.
+	// DefaultMaxFeedAge is used for platforms missing here.
+	MaxAge map[Platform]Duration `json:"max_age"`
+	// LotDropPercent is the decrease of the lot count, in percent, reported as a drop. Defaults to 10.
+	LotDropPercent float64 `json:"lot_drop_percent"`
+	// Baseline is the path of a baseline file whose issues are left out of the results.
+	Baseline string `json:"baseline"`
+	// Retry is the retry policy of feed downloads.
+	Retry RetryPolicy `json:"retry"`
+	// Proxy is the URL of an HTTP proxy the feeds are downloaded through, e.g. "env:FEEDS_PROXY".
+	Proxy Secret `json:"proxy"`
+	// Limits bound the size of the feeds and the time spent on them.
+	Limits Limits `json:"limits"`
+	// Alerts configures the notifiers used by NewAlerter.
+	Alerts AlertConfig `json:"alerts"`
+}
+
+type Project struct {
+	Name  string        `json:"name"`
+	Feeds []ProjectFeed `json:"feeds"`
+}
+
+type ProjectFeed struct {
+	Platform Platform `json:"platform"`
+	URL      string   `json:"url"`
+	Interval Duration `json:"interval"`
+	// Mirrors are tried in order when the feed can't be downloaded from URL.
+	Mirrors []string `json:"mirrors,omitempty"`
+	// Auth are the credentials of the feed and its mirrors.
+	Auth *SourceAuth `json:"auth,omitempty"`
+	// Proxy overrides MonitorConfig.Proxy for the feed.
+	Proxy Secret `json:"proxy,omitempty"`
+}
+
+// urls returns the URL of the feed followed by its mirrors.
+func (f ProjectFeed) urls() []string {
+	return append([]string{f.URL}, f.Mirrors...)
+}
+
+func LoadMonitorConfig(path string) (config MonitorConfig, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(data, &config)
+	return config, err
+}
+
+// FeedResult is the outcome of one fetch and check of a feed.
+type FeedResult struct {
+	Project  string   `json:"project"`
+	Platform Platform `json:"platform"`
+	// URL is the feed URL with its password redacted. Results are stored under it.
+	URL          string    `json:"url"`
+	CheckedAt    time.Time `json:"checked_at"`
+	LastModified time.Time `json:"last_modified"`
+	Error        string    `json:"error,omitempty"`
+	// ErrorKind tells a feed that can't be downloaded from a feed that can't be parsed.
+	ErrorKind ErrorKind `json:"error_kind,omitempty"`
+	// Attempts is the number of requests made to download the feed, and MirrorURL the mirror it came from, if any,
+	// with its password redacted.
+	Attempts  int     `json:"attempts"`
+	MirrorURL string  `json:"mirror_url,omitempty"`
+	Issues    []Issue `json:"issues,omitempty"`
+	// Suppressed is the number of issues suppressed by the baseline. They are not part of Issues.
+	Suppressed int `json:"suppressed,omitempty"`
+	LotCount   int `json:"lot_count"`
+}
+
+func (r FeedResult) key() string {
+	return r.Project + "|" + string(r.Platform) + "|" + r.URL
+}
+
+type EventType string
+
+const (
+	EventFeedUnavailable  EventType = "feed_unavailable"
+	EventFeedInvalid      EventType = "feed_invalid"
+	EventFeedRecovered    EventType = "feed_recovered"
+	EventIssuesIntroduced EventType = "issues_introduced"
+	EventIssuesResolved   EventType = "issues_resolved"
+	EventFeedStale        EventType = "feed_stale"
+	EventLotCountDropped  EventType = "lot_count_dropped"
+)
+
+// Event is a transition between two consecutive results of a feed.
+type Event struct {
+	Type     EventType
+	Previous FeedResult
+	Current  FeedResult
+	// Issues are the introduced or resolved issues.
+	Issues []Issue
+}
+
+// ResultStore keeps the latest result of every monitored feed. The url of Last is FeedResult.URL, redacted.
+type ResultStore interface {
+	Last(project string, platform Platform, url string) (result FeedResult, ok bool, err error)
+	Save(result FeedResult) error
+}
+
+// MemoryStore is a ResultStore that keeps results in memory.
+type MemoryStore struct {
+	mu      sync.Mutex
+	results map[string]FeedResult
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{results: make(map[string]FeedResult)}
+}
+
+func (s *MemoryStore) Last(project string, platform Platform, url string) (FeedResult, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, ok := s.results[FeedResult{Project: project, Platform: platform, URL: url}.key()]
+	return result, ok, nil
+}
+
+func (s *MemoryStore) Save(result FeedResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[result.key()] = result
+	return nil
+}
+
+// Results returns the stored results ordered by project, platform and URL.
+func (s *MemoryStore) Results() (results []FeedResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, result := range s.results {
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].key() < results[j].key()
+	})
+	return results
+}
+
+// FileStore is a ResultStore that keeps results in memory and writes them to a JSON file on every save.
+type FileStore struct {
+	MemoryStore
+	path string
+}
+
+func NewFileStore(path string) (*FileStore, error) {
+	store := &FileStore{MemoryStore: MemoryStore{results: make(map[string]FeedResult)}, path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var results []FeedResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		store.results[result.key()] = result
+	}
+	return store, nil
+}
+
+func (s *FileStore) Save(result FeedResult) error {
+	if err := s.MemoryStore.Save(result); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s.Results(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Monitor polls the feeds of the configured projects, stores the results and reports transitions.
+type Monitor struct {
+	Config MonitorConfig
+	Store  ResultStore
+	// OnEvent is called for every detected transition.
+	OnEvent func(Event)
+	// OnResult is called for every result before it is stored.
+	OnResult func(FeedResult)
+	// Baseline suppresses known issues in the results. NewMonitor doesn't load Config.Baseline.
+	Baseline *Baseline
+	// Now returns the time feeds are scheduled and checked at. Defaults to time.Now.
+	Now func() time.Time
+	// Fetcher downloads the feeds. NewMonitor sets it up with Config.Retry, Config.Proxy and Config.Limits; nil means DefaultFetcher.
+	// The credentials and proxy of a feed are added to a copy of it.
+	Fetcher *Fetcher
+	// Options are the options the feeds are checked with. Now defaults to the monitor clock and MaxAge to Config.MaxAge.
+	Options CheckOptions
+}
+
+type monitoredFeed struct {
+	project  string
+	feed     ProjectFeed
+	interval time.Duration
+	next     time.Time
+}
+
+func NewMonitor(config MonitorConfig, store ResultStore) *Monitor {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &Monitor{Config: config, Store: store, Fetcher: &Fetcher{Client: DefaultFetcher.Client, Retry: config.Retry, Proxy: config.Proxy, Limits: config.Limits}}
+}
+
+// Run polls the feeds until ctx is cancelled. Every feed is checked right away and then on its own interval.
+// The feeds that are due are checked together by CheckFeeds with Config.Workers and Config.PerHost.
+func (m *Monitor) Run(ctx context.Context) error {
+	feeds, err := m.feeds()
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		now := m.now()
+		var due []*monitoredFeed
+		for _, feed := range feeds {
+			if !now.Before(feed.next) {
+				due = append(due, feed)
+				feed.next = now.Add(feed.interval)
+			}
+		}
+		if len(due) > 0 {
+			m.checkDue(ctx, due)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkDue checks the feeds and records their results. Results of feeds cut short by ctx are dropped,
+// so that stopping the monitor is not reported as unavailable feeds.
+func (m *Monitor) checkDue(ctx context.Context, due []*monitoredFeed) {
+	sources := make([]ProjectFeed, len(due))
+	for idx, feed := range due {
+		sources[idx] = feed.feed
+	}
+	for idx, checked := range CheckFeeds(ctx, sources, m.batchOptions()) {
+		if checked.Err != nil && ctx.Err() != nil {
+			continue
+		}
+		feed := due[idx]
+		if _, err := m.record(feed.project, checked); err != nil {
+			log.Printf("monitor: project %s, %s feed %s: %v", feed.project, feed.feed.Platform, redactURL(feed.feed.URL), err)
+		}
+	}
+}
+
+func (m *Monitor) feeds() (feeds []*monitoredFeed, err error) {
+	interval := m.Config.Interval.Duration
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	for _, project := range m.Config.Projects {
+		for _, feed := range project.Feeds {
+			if _, err := url.Parse(feed.URL); err != nil {
+				return nil, fmt.Errorf("project %s: %w", project.Name, err)
+			}
+			if _, err := NewFeed(feed.Platform); err != nil {
+				return nil, fmt.Errorf("project %s: %w", project.Name, err)
+			}
+			monitored := &monitoredFeed{project: project.Name, feed: feed, interval: interval}
+			if feed.Interval.Duration > 0 {
+				monitored.interval = feed.Interval.Duration
+			}
+			feeds = append(feeds, monitored)
+		}
+	}
+	return feeds, nil
+}
+
+func (m *Monitor) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+// batchOptions returns the options of CheckFeeds: the config limits, the fetcher and Options with their defaults.
+func (m *Monitor) batchOptions() BatchOptions {
+	options := m.Options
+	if options.Now == nil {
+		options.Now = m.now
+	}
+	if options.MaxAge == nil && len(m.Config.MaxAge) > 0 {
+		options.MaxAge = make(map[Platform]time.Duration, len(m.Config.MaxAge))
+		for platform, maxAge := range m.Config.MaxAge {
+			options.MaxAge[platform] = maxAge.Duration
+		}
+	}
+	return BatchOptions{Workers: m.Config.Workers, PerHost: m.Config.PerHost, Fetcher: m.Fetcher, Check: options}
+}
+
+// Check fetches and checks one feed, stores the result and reports transitions from the previous result.
+// Fetch and parse failures are part of the result; the error is only returned when the store fails.
+func (m *Monitor) Check(project string, feed ProjectFeed) (result FeedResult, err error) {
+	return m.record(project, checkBatchFeed(context.Background(), feed, m.batchOptions()))
+}
+
+// record turns a checked feed into a result, stores it and reports transitions from the previous result.
+func (m *Monitor) record(project string, checked BatchResult) (result FeedResult, err error) {
+	feed := checked.Source
+	result = FeedResult{
+		Project:   project,
+		Platform:  feed.Platform,
+		URL:       redactURL(feed.URL),
+		CheckedAt: m.now(),
+		Attempts:  checked.Fetch.Attempts,
+	}
+	if checked.Err != nil {
+		result.Error = checked.Err.Error()
+		result.ErrorKind = ErrorKindOf(checked.Err)
+	} else {
+		if checked.Fetch.URL != result.URL {
+			result.MirrorURL = checked.Fetch.URL
+		}
+		result.LastModified, _ = FeedDate(checked.Feed)
+		result.Issues = checked.Issues
+		if m.Baseline != nil {
+			var suppressed []SuppressedIssue
+			result.Issues, suppressed = m.Baseline.Apply(result.Issues, result.CheckedAt)
+			result.Suppressed = len(suppressed)
+		}
+		lots, _ := checked.Feed.Lots()
+		result.LotCount = len(lots)
+	}
+
+	if m.OnResult != nil {
+		m.OnResult(result)
+	}
+
+	previous, ok, err := m.Store.Last(project, feed.Platform, result.URL)
+	if err != nil {
+		return result, fmt.Errorf("can't read previous result: %w", err)
+	}
+	if ok && m.OnEvent != nil {
+		for _, event := range m.Transitions(previous, result) {
+			m.OnEvent(event)
+		}
+	}
+	if err := m.Store.Save(result); err != nil {
+		return result, fmt.Errorf("can't save result: %w", err)
+	}
+	return result, nil
+}
+
+// Transitions compares two consecutive results of the same feed.
+func (m *Monitor) Transitions(previous FeedResult, current FeedResult) (events []Event) {
+	newEvent := func(eventType EventType, issues []Issue) Event {
+		return Event{Type: eventType, Previous: previous, Current: current, Issues: issues}
+	}
+
+	switch {
+	case current.Error != "":
+		// A feed going from unavailable to invalid, or back, is reported again.
+		if previous.Error == "" || (previous.ErrorKind != "" && previous.ErrorKind != current.ErrorKind) {
+			if current.ErrorKind == ErrorKindDecode || current.ErrorKind == ErrorKindEmpty || current.ErrorKind == ErrorKindLimit {
+				return append(events, newEvent(EventFeedInvalid, nil))
+			}
+			return append(events, newEvent(EventFeedUnavailable, nil))
+		}
+		return events
+	case previous.Error != "":
+		events = append(events, newEvent(EventFeedRecovered, nil))
+	}
+
+	if introduced := issuesDiff(current.Issues, previous.Issues); len(introduced) > 0 && previous.Error == "" {
+		events = append(events, newEvent(EventIssuesIntroduced, introduced))
+	}
+	if resolved := issuesDiff(previous.Issues, current.Issues); len(resolved) > 0 && previous.Error == "" {
+		events = append(events, newEvent(EventIssuesResolved, resolved))
+	}
+
+	if staleAfter := m.Config.StaleAfter.Duration; staleAfter > 0 && !current.LastModified.IsZero() {
+		wasStale := !previous.LastModified.IsZero() && previous.CheckedAt.Sub(previous.LastModified) > staleAfter
+		if current.CheckedAt.Sub(current.LastModified) > staleAfter && !wasStale {
+			events = append(events, newEvent(EventFeedStale, nil))
+		}
+	}
+
+	dropPercent := m.Config.LotDropPercent
+	if dropPercent <= 0 {
+		dropPercent = 10
+	}
+	if previous.LotCount > 0 && float64(previous.LotCount-current.LotCount)*100/float64(previous.LotCount) >= dropPercent {
+		events = append(events, newEvent(EventLotCountDropped, nil))
+	}
+	return events
+}
+
+// issuesDiff returns the issues of a that are not in b. Issues are compared by rule and message.
+func issuesDiff(a []Issue, b []Issue) (diff []Issue) {
+	known := make(map[string]bool, len(b))
+	for _, issue := range b {
+		known[issue.Rule+"\x00"+issue.Message] = true
+	}
+	for _, issue := range a {
+		if !known[issue.Rule+"\x00"+issue.Message] {
+			diff = append(diff, issue)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,266 @@
+package price_placements_feeds
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMonitorRedactsFeedURLs(t *testing.T) {
+	data, err := xml.Marshal(testAvitoFeed(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	feedURL := strings.Replace(server.URL, "http://", "http://user:secret@", 1)
+	feed := ProjectFeed{Platform: PlatformAvito, URL: feedURL + "/down.xml", Mirrors: []string{feedURL + "/avito.xml"}}
+	monitor := NewMonitor(MonitorConfig{}, nil)
+	monitor.Fetcher.Retry = RetryPolicy{MaxAttempts: 1}
+	result, err := monitor.Check("test", feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(encoded), "secret") {
+		t.Errorf("result contains the password: %s", encoded)
+	}
+	if want := strings.Replace(server.URL, "http://", "http://user:xxxxx@", 1) + "/avito.xml"; result.MirrorURL != want {
+		t.Errorf("MirrorURL = %s, want %s", result.MirrorURL, want)
+	}
+	if _, ok, _ := monitor.Store.Last("test", PlatformAvito, result.URL); !ok {
+		t.Error("result is not stored under its URL")
+	}
+}
+
+func TestFetchResultRedactsURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	fetcher := &Fetcher{Retry: RetryPolicy{MaxAttempts: 1}}
+	result, err := fetcher.FetchFeed(&AvitoFeed{}, strings.Replace(server.URL, "http://", "http://user:secret@", 1))
+	if err == nil {
+		t.Fatal("server error is not reported")
+	}
+	if strings.Contains(result.URL, "secret") || strings.Contains(err.Error(), "secret") {
+		t.Errorf("password is not redacted: %s, %v", result.URL, err)
+	}
+}
+
+func TestMonitorTransitions(t *testing.T) {
+	issue := func(message string) Issue { return Issue{Rule: "avito/required", Message: message} }
+	ok := FeedResult{CheckedAt: testNow, LastModified: testNow, LotCount: 100, Issues: []Issue{issue("a"), issue("b")}}
+
+	tests := []struct {
+		name     string
+		previous func(FeedResult) FeedResult
+		current  func(FeedResult) FeedResult
+		want     []EventType
+	}{
+		{"unchanged", nil, nil, nil},
+		{"unavailable", nil, func(r FeedResult) FeedResult {
+			r.Error, r.ErrorKind = "502 Bad Gateway", ErrorKindHTTPStatus
+			return r
+		}, []EventType{EventFeedUnavailable}},
+		{"invalid", nil, func(r FeedResult) FeedResult {
+			r.Error, r.ErrorKind = "XML syntax error", ErrorKindDecode
+			return r
+		}, []EventType{EventFeedInvalid}},
+		{"still unavailable", func(r FeedResult) FeedResult {
+			r.Error, r.ErrorKind = "502 Bad Gateway", ErrorKindHTTPStatus
+			return r
+		}, func(r FeedResult) FeedResult {
+			r.Error, r.ErrorKind = "503 Service Unavailable", ErrorKindHTTPStatus
+			return r
+		}, nil},
+		{"recovered", func(r FeedResult) FeedResult {
+			r.Error, r.ErrorKind, r.Issues = "502 Bad Gateway", ErrorKindHTTPStatus, nil
+			return r
+		}, nil, []EventType{EventFeedRecovered}},
+		{"issues changed", nil, func(r FeedResult) FeedResult {
+			r.Issues = []Issue{issue("b"), issue("c")}
+			return r
+		}, []EventType{EventIssuesIntroduced, EventIssuesResolved}},
+		{"stale", nil, func(r FeedResult) FeedResult {
+			r.CheckedAt = r.CheckedAt.Add(49 * time.Hour)
+			return r
+		}, []EventType{EventFeedStale}},
+		{"lot count dropped", nil, func(r FeedResult) FeedResult {
+			r.LotCount = 90
+			return r
+		}, []EventType{EventLotCountDropped}},
+		{"lot count decreased a little", nil, func(r FeedResult) FeedResult {
+			r.LotCount = 91
+			return r
+		}, nil},
+	}
+
+	monitor := NewMonitor(MonitorConfig{StaleAfter: Duration{48 * time.Hour}}, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous, current := ok, ok
+			if tt.previous != nil {
+				previous = tt.previous(ok)
+			}
+			if tt.current != nil {
+				current = tt.current(ok)
+			}
+			var got []EventType
+			for _, event := range monitor.Transitions(previous, current) {
+				got = append(got, event.Type)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Transitions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonitorCheckStoresResultsAndReportsEvents(t *testing.T) {
+	var feeds [][]byte
+	for _, ads := range []int{20, 12} {
+		data, err := xml.Marshal(testAvitoFeed(ads))
+		if err != nil {
+			t.Fatal(err)
+		}
+		feeds = append(feeds, data)
+	}
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", testNow.Format(http.TimeFormat))
+		w.Write(feeds[atomic.AddInt32(&requests, 1)-1])
+	}))
+	defer server.Close()
+
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "results.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	monitor := NewMonitor(MonitorConfig{}, store)
+	monitor.Now = testClock
+	var events []EventType
+	monitor.OnEvent = func(event Event) { events = append(events, event.Type) }
+
+	feed := ProjectFeed{Platform: PlatformAvito, URL: server.URL + "/avito.xml"}
+	for i := 0; i < 2; i++ {
+		result, err := monitor.Check("park", feed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Error != "" || result.Attempts != 1 || !result.LastModified.Equal(testNow) {
+			t.Fatalf("result = %+v", result)
+		}
+	}
+	if want := []EventType{EventLotCountDropped}; !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+
+	reopened, err := NewFileStore(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, ok, err := reopened.Last("park", PlatformAvito, feed.URL)
+	if err != nil || !ok || last.LotCount != 12 {
+		t.Errorf("stored result = %+v, %v, %v", last, ok, err)
+	}
+}
+
+func TestMonitorRunLimitsHostConcurrency(t *testing.T) {
+	data, err := xml.Marshal(testAvitoFeed(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var active, maxActive int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := atomic.AddInt32(&active, 1); n > atomic.LoadInt32(&maxActive) {
+			atomic.StoreInt32(&maxActive, n)
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	config := MonitorConfig{Workers: 3, PerHost: 1, Projects: []Project{{Name: "park", Feeds: []ProjectFeed{
+		{Platform: PlatformAvito, URL: server.URL + "/1.xml"},
+		{Platform: PlatformAvito, URL: server.URL + "/2.xml"},
+		{Platform: PlatformAvito, URL: server.URL + "/3.xml"},
+	}}}}
+	store := NewMemoryStore()
+	monitor := NewMonitor(config, store)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var results int
+	monitor.OnResult = func(result FeedResult) {
+		if results++; results == 3 {
+			cancel()
+		}
+	}
+	if err := monitor.Run(ctx); err != context.Canceled {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	if got := len(store.Results()); got != 3 {
+		t.Errorf("stored results = %d, want 3", got)
+	}
+	if got := atomic.LoadInt32(&maxActive); got != 1 {
+		t.Errorf("concurrent requests to the host = %d, want 1", got)
+	}
+}
+
+func TestMonitorCheckUsesOptions(t *testing.T) {
+	data, err := xml.Marshal(testAvitoFeed(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", testNow.Add(-36*time.Hour).Format(http.TimeFormat))
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	config := MonitorConfig{MaxAge: map[Platform]Duration{PlatformAvito: {48 * time.Hour}}}
+	monitor := NewMonitor(config, nil)
+	monitor.Now = testClock
+	monitor.Options = CheckOptions{
+		Descriptions: map[Platform]DescriptionRule{PlatformAvito: {StopWords: []string{"парк"}}},
+		Workers:      4,
+	}
+	result, err := monitor.Check("park", ProjectFeed{Platform: PlatformAvito, URL: server.URL + "/avito.xml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Issues) != 12 {
+		t.Fatalf("issues = %+v", result.Issues)
+	}
+	for _, issue := range result.Issues {
+		if issue.Rule != "avito/descriptions" {
+			t.Errorf("issue = %+v", issue)
+		}
+	}
+}
@@ -0,0 +1,493 @@
+package price_placements_feeds
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Notifier delivers an alert text about a monitor event.
+type Notifier interface {
+	Notify(ctx context.Context, event Event, text string) error
+}
+
+// notifyTimeout bounds the delivery of an alert by one notifier.
+const notifyTimeout = 30 * time.Second
+
+// notifyClient sends the alerts of notifiers without a Client.
+var notifyClient = &http.Client{Timeout: notifyTimeout}
+
+// WebhookNotifier posts the event as JSON to a generic webhook.
+type WebhookNotifier struct {
+	URL     Secret
+	Headers map[string]Secret
+	// Client defaults to a client with a 30s timeout.
+	Client *http.Client
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, event Event, text string) error {
+	payload := struct {
+		Type      EventType `json:"type"`
+		Project   string    `json:"project"`
+		Platform  Platform  `json:"platform"`
+		URL       string    `json:"url"`
+		Text      string    `json:"text"`
+		Error     string    `json:"error,omitempty"`
+		ErrorKind ErrorKind `json:"error_kind,omitempty"`
+		Issues    []Issue   `json:"issues,omitempty"`
+		LotCount  int       `json:"lot_count"`
+	}{event.Type, event.Current.Project, event.Current.Platform, event.Current.URL, text, event.Current.Error, event.Current.ErrorKind, event.Issues, event.Current.LotCount}
+
+	endpoint, err := n.URL.Resolve()
+	if err != nil {
+		return err
+	}
+	headers := make(map[string]string, len(n.Headers))
+	for key, value := range n.Headers {
+		if headers[key], err = value.Resolve(); err != nil {
+			return fmt.Errorf("header %s: %w", key, err)
+		}
+	}
+	return postJSON(ctx, n.Client, endpoint, headers, payload)
+}
+
+// TelegramNotifier sends the alert text through a Telegram bot.
+type TelegramNotifier struct {
+	Token  Secret
+	ChatID string
+	// BaseURL defaults to https://api.telegram.org.
+	BaseURL string
+	// Client defaults to a client with a 30s timeout.
+	Client *http.Client
+}
+
+func (n *TelegramNotifier) Notify(ctx context.Context, event Event, text string) error {
+	baseURL := n.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.telegram.org"
+	}
+	token, err := n.Token.Resolve()
+	if err != nil {
+		return err
+	}
+	payload := map[string]any{
+		"chat_id":                  n.ChatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	}
+	return postJSON(ctx, n.Client, strings.TrimRight(baseURL, "/")+"/bot"+token+"/sendMessage", nil, payload)
+}
+
+// SlackNotifier posts the alert text to a Slack-compatible incoming webhook.
+type SlackNotifier struct {
+	WebhookURL Secret
+	// Client defaults to a client with a 30s timeout.
+	Client *http.Client
+}
+
+func (n *SlackNotifier) Notify(ctx context.Context, event Event, text string) error {
+	endpoint, err := n.WebhookURL.Resolve()
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, n.Client, endpoint, nil, map[string]string{"text": text})
+}
+
+// postJSON posts the payload to the endpoint within notifyTimeout. The errors leave out the endpoint,
+// which contains the Telegram bot token or the secret of the webhook.
+func postJSON(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, payload any) error {
+	if client == nil {
+		client = notifyClient
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid notification URL. Error:%w", withoutURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("can't send notification. Error:%w", withoutURL(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notification not accepted. Status:%s", resp.Status)
+	}
+	return nil
+}
+
+// withoutURL returns the error a *url.Error wraps, leaving out the URL.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// DefaultAlertTemplate renders the platform, project, event and the largest groups of issues.
+const DefaultAlertTemplate = `[{{.Platform}}] {{.Project}}: {{.Title}}
+{{.URL}}
+{{- if .Error}}
+{{.Error}}
+{{- end}}
+{{- range .Summary}}
+- {{.}}
+{{- end}}
+{{- if .MoreIssues}}
+... and {{.MoreIssues}} more
+{{- end}}`
+
+var alertTitles = map[EventType]string{
+	EventFeedUnavailable:  "feed is unavailable",
+	EventFeedInvalid:      "feed can't be parsed",
+	EventFeedRecovered:    "feed is available again",
+	EventIssuesIntroduced: "new issues",
+	EventIssuesResolved:   "issues resolved",
+	EventFeedStale:        "feed is not updated",
+	EventLotCountDropped:  "lot count dropped",
+}
+
+// Alerter renders monitor events and sends them to notifiers, skipping duplicates and holding alerts back in quiet hours.
+type Alerter struct {
+	Notifiers []Notifier
+	// Types are the event types sent. Defaults to unavailable, invalid, issues introduced, stale and lot count drop.
+	Types []EventType
+	// Template is a text/template executed with the alert fields. Defaults to DefaultAlertTemplate.
+	Template *template.Template
+	// TopIssues is the number of issues listed in the alert. Defaults to 5.
+	TopIssues int
+	// DedupWindow suppresses an identical alert sent within the window. Defaults to 6h.
+	DedupWindow time.Duration
+	// QuietHours holds alerts back in the given local time range, e.g. 22:00-08:00, until Flush.
+	QuietHours *QuietHours
+	// Now returns the time quiet hours and duplicates are judged by. Defaults to time.Now.
+	Now func() time.Time
+
+	mu   sync.Mutex
+	sent map[string]time.Time
+	// pending are the events held back by quiet hours.
+	pending []Event
+}
+
+// QuietHours is a daily time range in Location (local time when nil). From may be later than To.
+type QuietHours struct {
+	From     time.Duration
+	To       time.Duration
+	Location *time.Location
+	// Allow lists event types that are sent during quiet hours.
+	Allow []EventType
+}
+
+// ParseQuietHours parses a range like "22:00-08:00".
+func ParseQuietHours(s string) (*QuietHours, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("quiet hours must look like 22:00-08:00: %s", s)
+	}
+	fromTime, err := time.Parse("15:04", strings.TrimSpace(from))
+	if err != nil {
+		return nil, err
+	}
+	toTime, err := time.Parse("15:04", strings.TrimSpace(to))
+	if err != nil {
+		return nil, err
+	}
+	return &QuietHours{
+		From: time.Duration(fromTime.Hour())*time.Hour + time.Duration(fromTime.Minute())*time.Minute,
+		To:   time.Duration(toTime.Hour())*time.Hour + time.Duration(toTime.Minute())*time.Minute,
+	}, nil
+}
+
+func (q *QuietHours) contains(t time.Time, eventType EventType) bool {
+	for _, allowed := range q.Allow {
+		if allowed == eventType {
+			return false
+		}
+	}
+	if q.Location != nil {
+		t = t.In(q.Location)
+	}
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if q.From <= q.To {
+		return sinceMidnight >= q.From && sinceMidnight < q.To
+	}
+	return sinceMidnight >= q.From || sinceMidnight < q.To
+}
+
+type alertData struct {
+	Type      EventType
+	Title     string
+	Project   string
+	Platform  Platform
+	URL       string
+	Error     string
+	LotCount  int
+	Issues    []Issue
+	TopIssues []Issue
+	// Summary groups the issues by rule and field, see IssueSummary.
+	Summary    []IssueSummary
+	MoreIssues int
+}
+
+// Render returns the alert text of an event.
+func (a *Alerter) Render(event Event) (string, error) {
+	tmpl := a.Template
+	if tmpl == nil {
+		tmpl = template.Must(template.New("alert").Parse(DefaultAlertTemplate))
+	}
+	top := a.TopIssues
+	if top <= 0 {
+		top = 5
+	}
+
+	data := alertData{
+		Type:     event.Type,
+		Title:    alertTitles[event.Type],
+		Project:  event.Current.Project,
+		Platform: event.Current.Platform,
+		URL:      event.Current.URL,
+		Error:    event.Current.Error,
+		LotCount: event.Current.LotCount,
+		Issues:   event.Issues,
+	}
+	if event.Type == EventLotCountDropped {
+		data.Title = fmt.Sprintf("lot count dropped from %d to %d", event.Previous.LotCount, event.Current.LotCount)
+	}
+	if event.Type == EventFeedStale {
+		data.Title = fmt.Sprintf("feed is not updated since %s", event.Current.LastModified.Format("2006-01-02 15:04"))
+	}
+	data.TopIssues = event.Issues
+	if len(data.TopIssues) > top {
+		data.TopIssues = data.TopIssues[:top]
+	}
+	data.Summary = SummarizeIssues(event.Issues, event.Current.LotCount, 3)
+	if len(data.Summary) > top {
+		data.MoreIssues = len(data.Summary) - top
+		data.Summary = data.Summary[:top]
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Alert sends an event to all notifiers unless it is filtered or duplicated. An event that falls into
+// quiet hours is held back and sent by Flush when they are over.
+// An alert counts as sent when at least one notifier accepted it; the errors of the others are joined.
+func (a *Alerter) Alert(ctx context.Context, event Event) (sent bool, err error) {
+	if !a.wants(event.Type) {
+		return false, nil
+	}
+
+	now := a.now()
+	if a.QuietHours != nil && a.QuietHours.contains(now, event.Type) {
+		a.mu.Lock()
+		a.pending = append(a.pending, event)
+		a.mu.Unlock()
+		return false, nil
+	}
+	return a.send(ctx, event, now)
+}
+
+// Flush sends the events held back by quiet hours that are over and returns the number of alerts sent.
+// OnEvent flushes before every event; call Flush periodically to send them when no new events come.
+func (a *Alerter) Flush(ctx context.Context) (sent int, err error) {
+	now := a.now()
+	a.mu.Lock()
+	var due, held []Event
+	for _, event := range a.pending {
+		if a.QuietHours != nil && a.QuietHours.contains(now, event.Type) {
+			held = append(held, event)
+		} else {
+			due = append(due, event)
+		}
+	}
+	a.pending = held
+	a.mu.Unlock()
+
+	var errs []string
+	for _, event := range due {
+		ok, err := a.send(ctx, event, now)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		if ok {
+			sent++
+		}
+	}
+	if len(errs) > 0 {
+		return sent, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return sent, nil
+}
+
+// send renders the event and sends it to the notifiers. The event key is reserved before sending,
+// so that concurrent calls don't send the same alert twice, and released when no notifier accepted it.
+func (a *Alerter) send(ctx context.Context, event Event, now time.Time) (sent bool, err error) {
+	window := a.DedupWindow
+	if window <= 0 {
+		window = 6 * time.Hour
+	}
+	key := string(event.Type) + "\n" + event.Current.key()
+	for _, issue := range event.Issues {
+		key += "\n" + issue.Message
+	}
+
+	a.mu.Lock()
+	if last, duplicate := a.sent[key]; duplicate && now.Sub(last) < window {
+		a.mu.Unlock()
+		return false, nil
+	}
+	if a.sent == nil {
+		a.sent = make(map[string]time.Time)
+	}
+	for k, t := range a.sent {
+		if now.Sub(t) >= window {
+			delete(a.sent, k)
+		}
+	}
+	a.sent[key] = now
+	a.mu.Unlock()
+
+	defer func() {
+		if !sent {
+			a.mu.Lock()
+			if last, ok := a.sent[key]; ok && last.Equal(now) {
+				delete(a.sent, key)
+			}
+			a.mu.Unlock()
+		}
+	}()
+
+	text, err := a.Render(event)
+	if err != nil {
+		return false, err
+	}
+
+	var errs []string
+	for _, notifier := range a.Notifiers {
+		if err := notifier.Notify(ctx, event, text); err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			sent = true
+		}
+	}
+	if len(errs) > 0 {
+		return sent, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return sent, nil
+}
+
+// OnEvent can be used as Monitor.OnEvent. It first sends the events held back by quiet hours that are over.
+// Delivery errors are logged.
+func (a *Alerter) OnEvent(event Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(len(a.Notifiers)+1)*notifyTimeout)
+	defer cancel()
+	if _, err := a.Flush(ctx); err != nil {
+		log.Printf("alerts held back by quiet hours: %v", err)
+	}
+	if _, err := a.Alert(ctx, event); err != nil {
+		log.Printf("alert %s for %s: %v", event.Type, event.Current.URL, err)
+	}
+}
+
+func (a *Alerter) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
+
+func (a *Alerter) wants(eventType EventType) bool {
+	types := a.Types
+	if len(types) == 0 {
+		types = []EventType{EventFeedUnavailable, EventFeedInvalid, EventIssuesIntroduced, EventFeedStale, EventLotCountDropped}
+	}
+	for _, t := range types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// AlertConfig describes the notifiers of the monitor in MonitorConfig.
+type AlertConfig struct {
+	Webhooks []struct {
+		URL     Secret            `json:"url"`
+		Headers map[string]Secret `json:"headers"`
+	} `json:"webhooks"`
+	Telegram []struct {
+		Token   Secret `json:"token"`
+		ChatID  string `json:"chat_id"`
+		BaseURL string `json:"base_url"`
+	} `json:"telegram"`
+	Slack []struct {
+		WebhookURL Secret `json:"webhook_url"`
+	} `json:"slack"`
+	Types       []EventType `json:"types"`
+	TopIssues   int         `json:"top_issues"`
+	DedupWindow Duration    `json:"dedup_window"`
+	// QuietHours is a local time range like "22:00-08:00".
+	QuietHours      string      `json:"quiet_hours"`
+	QuietHoursAllow []EventType `json:"quiet_hours_allow"`
+	// Template overrides DefaultAlertTemplate.
+	Template string `json:"template"`
+}
+
+// NewAlerter builds an Alerter from the config.
+func NewAlerter(config AlertConfig) (*Alerter, error) {
+	alerter := &Alerter{
+		Types:       config.Types,
+		TopIssues:   config.TopIssues,
+		DedupWindow: config.DedupWindow.Duration,
+	}
+	for _, webhook := range config.Webhooks {
+		alerter.Notifiers = append(alerter.Notifiers, &WebhookNotifier{URL: webhook.URL, Headers: webhook.Headers})
+	}
+	for _, telegram := range config.Telegram {
+		alerter.Notifiers = append(alerter.Notifiers, &TelegramNotifier{Token: telegram.Token, ChatID: telegram.ChatID, BaseURL: telegram.BaseURL})
+	}
+	for _, slack := range config.Slack {
+		alerter.Notifiers = append(alerter.Notifiers, &SlackNotifier{WebhookURL: slack.WebhookURL})
+	}
+
+	if config.QuietHours != "" {
+		quietHours, err := ParseQuietHours(config.QuietHours)
+		if err != nil {
+			return nil, err
+		}
+		quietHours.Allow = config.QuietHoursAllow
+		alerter.QuietHours = quietHours
+	}
+	if config.Template != "" {
+		tmpl, err := template.New("alert").Parse(config.Template)
+		if err != nil {
+			return nil, err
+		}
+		alerter.Template = tmpl
+	}
+	return alerter, nil
+}
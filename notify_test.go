@@ -0,0 +1,229 @@
+package price_placements_feeds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testEvent() Event {
+	current := FeedResult{Project: "park", Platform: PlatformAvito, URL: "https://example.com/avito.xml", LotCount: 20}
+	return Event{Type: EventIssuesIntroduced, Current: current, Issues: []Issue{
+		{Rule: "avito/required", Field: "Ad.Price", LotID: "ad-1", Message: "field Ad.Price is empty. InternalID: ad-1"},
+		{Rule: "avito/required", Field: "Ad.Price", LotID: "ad-2", Message: "field Ad.Price is empty. InternalID: ad-2"},
+	}}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got struct {
+		Type   EventType `json:"type"`
+		Text   string    `json:"text"`
+		Issues []Issue   `json:"issues"`
+	}
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_WEBHOOK_TOKEN", "Bearer abc")
+	notifier := &WebhookNotifier{URL: Secret(server.URL), Headers: map[string]Secret{"Authorization": "env:TEST_WEBHOOK_TOKEN"}}
+	if err := notifier.Notify(context.Background(), testEvent(), "new issues"); err != nil {
+		t.Fatal(err)
+	}
+	if token != "Bearer abc" {
+		t.Errorf("Authorization = %q", token)
+	}
+	if got.Type != EventIssuesIntroduced || got.Text != "new issues" || len(got.Issues) != 2 || got.Issues[0].LotID != "ad-1" {
+		t.Errorf("payload = %+v", got)
+	}
+}
+
+func TestTelegramNotifierHidesToken(t *testing.T) {
+	const token = "123456:secret-token"
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+
+	notifier := &TelegramNotifier{Token: token, ChatID: "42", BaseURL: server.URL}
+	err := notifier.Notify(context.Background(), testEvent(), "text")
+	if err == nil || strings.Contains(err.Error(), token) {
+		t.Errorf("rejected notification error = %v", err)
+	}
+	if path != "/bot"+token+"/sendMessage" {
+		t.Errorf("path = %s", path)
+	}
+
+	server.Close()
+	err = notifier.Notify(context.Background(), testEvent(), "text")
+	if err == nil || strings.Contains(err.Error(), token) {
+		t.Errorf("failed notification error = %v", err)
+	}
+
+	notifier.BaseURL = "http://example.com/%zz"
+	err = notifier.Notify(context.Background(), testEvent(), "text")
+	if err == nil || strings.Contains(err.Error(), token) {
+		t.Errorf("invalid URL error = %v", err)
+	}
+}
+
+func TestNotifierTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	notifier := &SlackNotifier{WebhookURL: Secret(server.URL + "/hooks/secret"), Client: &http.Client{Timeout: 50 * time.Millisecond}}
+	start := time.Now()
+	err := notifier.Notify(context.Background(), testEvent(), "text")
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("notification took %s", elapsed)
+	}
+	if notifyClient.Timeout <= 0 {
+		t.Error("default notification client has no timeout")
+	}
+}
+
+func TestAlertConfigSecrets(t *testing.T) {
+	var config AlertConfig
+	data := `{"telegram": [{"token": "123:abc", "chat_id": "1"}], "slack": [{"webhook_url": "env:SLACK_URL"}]}`
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(encoded), "123:abc") || !strings.Contains(string(encoded), "env:SLACK_URL") {
+		t.Errorf("encoded config = %s", encoded)
+	}
+
+	alerter, err := NewAlerter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if telegram := alerter.Notifiers[0].(*TelegramNotifier); telegram.Token != "123:abc" {
+		t.Errorf("token = %q", telegram.Token)
+	}
+}
+
+func TestAlerterRendersAndDeduplicates(t *testing.T) {
+	var texts []string
+	notifier := notifierFunc(func(ctx context.Context, event Event, text string) error {
+		texts = append(texts, text)
+		return nil
+	})
+	alerter := &Alerter{Notifiers: []Notifier{notifier}, Now: testClock}
+
+	for i := 0; i < 2; i++ {
+		if _, err := alerter.Alert(context.Background(), testEvent()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "[avito] park: new issues\nhttps://example.com/avito.xml\n- Ad.Price (avito/required) in 10% of lots (2 lots, e.g. ad-1, ad-2)"
+	if len(texts) != 1 || texts[0] != want {
+		t.Errorf("alerts = %q, want %q", texts, want)
+	}
+}
+
+type notifierFunc func(ctx context.Context, event Event, text string) error
+
+func (f notifierFunc) Notify(ctx context.Context, event Event, text string) error {
+	return f(ctx, event, text)
+}
+
+func TestAlerterHoldsAlertsInQuietHours(t *testing.T) {
+	var types []EventType
+	notifier := notifierFunc(func(ctx context.Context, event Event, text string) error {
+		types = append(types, event.Type)
+		return nil
+	})
+	now := testNow
+	alerter := &Alerter{
+		Notifiers:  []Notifier{notifier},
+		QuietHours: &QuietHours{From: 11 * time.Hour, To: 13 * time.Hour, Location: time.UTC},
+		Now:        func() time.Time { return now },
+	}
+
+	unavailable := Event{Type: EventFeedUnavailable, Current: FeedResult{Project: "park", Platform: PlatformAvito, URL: "https://example.com/avito.xml"}}
+	if sent, err := alerter.Alert(context.Background(), unavailable); sent || err != nil {
+		t.Fatalf("Alert() in quiet hours = %v, %v", sent, err)
+	}
+	if sent, err := alerter.Flush(context.Background()); sent != 0 || err != nil || len(types) != 0 {
+		t.Fatalf("Flush() in quiet hours = %d, %v, alerts %v", sent, err, types)
+	}
+
+	now = testNow.Add(2 * time.Hour)
+	if sent, err := alerter.Flush(context.Background()); sent != 1 || err != nil {
+		t.Fatalf("Flush() after quiet hours = %d, %v", sent, err)
+	}
+	if sent, _ := alerter.Flush(context.Background()); sent != 0 {
+		t.Errorf("second Flush() = %d", sent)
+	}
+	if want := []EventType{EventFeedUnavailable}; !reflect.DeepEqual(types, want) {
+		t.Errorf("alerts = %v, want %v", types, want)
+	}
+}
+
+func TestAlerterSendsConcurrentDuplicatesOnce(t *testing.T) {
+	var count int32
+	notifier := notifierFunc(func(ctx context.Context, event Event, text string) error {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	alerter := &Alerter{Notifiers: []Notifier{notifier}, Now: testClock}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			alerter.OnEvent(testEvent())
+		}()
+	}
+	wg.Wait()
+	if count != 1 {
+		t.Errorf("alerts sent = %d, want 1", count)
+	}
+}
+
+func TestAlerterRetriesFailedAlerts(t *testing.T) {
+	fail := true
+	notifier := notifierFunc(func(ctx context.Context, event Event, text string) error {
+		if fail {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	alerter := &Alerter{Notifiers: []Notifier{notifier}, Now: testClock}
+
+	if sent, err := alerter.Alert(context.Background(), testEvent()); sent || err == nil {
+		t.Fatalf("Alert() = %v, %v", sent, err)
+	}
+	fail = false
+	if sent, err := alerter.Alert(context.Background(), testEvent()); !sent || err != nil {
+		t.Errorf("Alert() after a failure = %v, %v", sent, err)
+	}
+}
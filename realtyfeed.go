@@ -4,218 +4,880 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
-	"log"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type RealtyFeed struct {
-	LastModified   time.Time
-	Xmlns          string  `xml:"xmlns,attr"`
-	GenerationDate string  `xml:"generation-date"`
-	Offer          []Offer `xml:"offer"`
+	LastModified   time.Time `xml:"-"`
+	XMLName        xml.Name
+	Xmlns          string  `xml:"xmlns,attr,omitempty"`
+	GenerationDate string  `xml:"generation-date,omitempty"`
+	Offer          []Offer `xml:"offer,omitempty"`
+
+	Unknown     []UnknownElement `xml:",any"`
+	UnknownAttr []xml.Attr       `xml:",any,attr"`
+	Strict      bool             `xml:"-"` // Check also reports unknown elements and attributes
 }
 
 type Offer struct {
-	InternalID string `xml:"internal-id,attr"`
-
-	Image []struct {
-		Tag string `xml:"tag,attr"`
-	} `xml:"image"`
-
-	Type           string   `xml:"type"`
-	PropertyType   string   `xml:"property-type"`
-	Category       string   `xml:"category"`
-	URL            string   `xml:"url"`
-	WindowView     string   `xml:"window-view"`
-	CeilingHeight  []string `xml:"ceiling-height"`
-	Description    string   `xml:"description"`
-	CreationDate   string   `xml:"creation-date"`
-	Vas            []vas    `xml:"vas"`
-	LastUpdateDate string   `xml:"last-update-date"`
-	ExpireDate     string   `xml:"expire-date"`
+	InternalID string `xml:"internal-id,attr,omitempty"`
+
+	Image []RealtyImage `xml:"image,omitempty"`
+
+	Type           string   `xml:"type,omitempty"`
+	PropertyType   string   `xml:"property-type,omitempty"`
+	Category       string   `xml:"category,omitempty"`
+	URL            string   `xml:"url,omitempty"`
+	WindowView     string   `xml:"window-view,omitempty"`
+	CeilingHeight  []string `xml:"ceiling-height,omitempty"`
+	Description    string   `xml:"description,omitempty"`
+	CreationDate   string   `xml:"creation-date,omitempty"`
+	Vas            []vas    `xml:"vas,omitempty"`
+	LastUpdateDate string   `xml:"last-update-date,omitempty"`
+	ExpireDate     string   `xml:"expire-date,omitempty"`
 	Location       struct {
-		Country      string `xml:"country"`
-		Region       string `xml:"region"`
-		Address      string `xml:"address"`
-		LocalityName string `xml:"locality-name"`
-		Latitude     string `xml:"latitude"`
-		Longitude    string `xml:"longitude"`
-		Direction    string `xml:"direction"`
-		Distance     string `xml:"distance"`
+		Country      string `xml:"country,omitempty"`
+		Region       string `xml:"region,omitempty"`
+		Address      string `xml:"address,omitempty"`
+		LocalityName string `xml:"locality-name,omitempty"`
+		Latitude     string `xml:"latitude,omitempty"`
+		Longitude    string `xml:"longitude,omitempty"`
+		Direction    string `xml:"direction,omitempty"`
+		Distance     string `xml:"distance,omitempty"`
 		Metro        struct {
-			Name            string `xml:"name"`
-			TimeOnTransport string `xml:"time-on-transport"`
-			TimeOnFoot      string `xml:"time-on-foot"`
-		} `xml:"metro"`
-	} `xml:"location"`
+			Name            string `xml:"name,omitempty"`
+			TimeOnTransport string `xml:"time-on-transport,omitempty"`
+			TimeOnFoot      string `xml:"time-on-foot,omitempty"`
+		} `xml:"metro,omitempty"`
+	} `xml:"location,omitempty"`
 	SalesAgent struct {
-		Category     string `xml:"category"`
-		Organization string `xml:"organization"`
-		Phone        string `xml:"phone"`
-	} `xml:"sales-agent"`
+		Category     string `xml:"category,omitempty"`
+		Organization string `xml:"organization,omitempty"`
+		Phone        string `xml:"phone,omitempty"`
+	} `xml:"sales-agent,omitempty"`
 	Price struct {
-		Value    float32 `xml:"value"`
-		Currency string  `xml:"currency"`
-	} `xml:"price"`
-	NewFlat          string      `xml:"new-flat"`
-	DealStatus       string      `xml:"deal-status"`
-	BuiltYear        int64       `xml:"built-year"`
-	ReadyQuarter     int64       `xml:"ready-quarter"`
-	Area             Value       `xml:"area"`
-	RoomSpace        []Value     `xml:"room-space"`
-	LivingSpace      Value       `xml:"living-space"`
-	KitchenSpace     Value       `xml:"kitchen-space"`
-	Renovation       string      `xml:"renovation"`
-	Rooms            int64       `xml:"rooms"`
-	RubbishChute     string      `xml:"rubbish-chute"`
-	FloorsTotal      int64       `xml:"floors-total"`
-	Floor            int64       `xml:"floor"`
-	BuildingName     string      `xml:"building-name"`
-	VillageName      string      `xml:"village-name"`
-	BuildingType     string      `xml:"building-type"`
-	Mortgage         string      `xml:"mortgage"`
-	BuildingState    string      `xml:"building-state"`
-	Lift             string      `xml:"lift"`
-	BathroomUnit     string      `xml:"bathroom-unit"`
-	YandexBuildingID int64       `xml:"yandex-building-id"`
-	YandexVillageID  int64       `xml:"yandex-village-id"`
-	YandexHouseID    CustomInt64 `xml:"yandex-house-id"`
-	BuildingSection  string      `xml:"building-section"`
-	Balcony          string      `xml:"balcony"`
-	OpenPlan         string      `xml:"open-plan"`
+		Value    float32 `xml:"value,omitempty"`
+		Currency string  `xml:"currency,omitempty"`
+		Period   string  `xml:"period,omitempty"`
+		Unit     string  `xml:"unit,omitempty"`
+	} `xml:"price,omitempty"`
+	NewFlat          string      `xml:"new-flat,omitempty"`
+	DealStatus       string      `xml:"deal-status,omitempty"`
+	BuiltYear        int64       `xml:"built-year,omitempty"`
+	ReadyQuarter     int64       `xml:"ready-quarter,omitempty"`
+	Area             Value       `xml:"area,omitempty"`
+	RoomSpace        []Value     `xml:"room-space,omitempty"`
+	LivingSpace      Value       `xml:"living-space,omitempty"`
+	KitchenSpace     Value       `xml:"kitchen-space,omitempty"`
+	Renovation       string      `xml:"renovation,omitempty"`
+	Rooms            int64       `xml:"rooms,omitempty"`
+	RubbishChute     string      `xml:"rubbish-chute,omitempty"`
+	FloorsTotal      int64       `xml:"floors-total,omitempty"`
+	Floor            int64       `xml:"floor,omitempty"`
+	BuildingName     string      `xml:"building-name,omitempty"`
+	VillageName      string      `xml:"village-name,omitempty"`
+	BuildingType     string      `xml:"building-type,omitempty"`
+	Mortgage         string      `xml:"mortgage,omitempty"`
+	BuildingState    string      `xml:"building-state,omitempty"`
+	Lift             string      `xml:"lift,omitempty"`
+	BathroomUnit     string      `xml:"bathroom-unit,omitempty"`
+	YandexBuildingID int64       `xml:"yandex-building-id,omitempty"`
+	YandexVillageID  int64       `xml:"yandex-village-id,omitempty"`
+	YandexHouseID    CustomInt64 `xml:"yandex-house-id,omitempty"`
+	BuildingSection  string      `xml:"building-section,omitempty"`
+	Balcony          string      `xml:"balcony,omitempty"`
+	OpenPlan         string      `xml:"open-plan,omitempty"`
+	Studio           string      `xml:"studio,omitempty"`
+	RoomsOffered     int64       `xml:"rooms-offered,omitempty"`
+	RoomsType        string      `xml:"rooms-type,omitempty"`
+	BathroomUnitNum  int64       `xml:"bathroom-unit-count,omitempty"`
+
+	// Rent
+	RentPledge        string `xml:"rent-pledge,omitempty"`
+	Prepayment        string `xml:"prepayment,omitempty"`
+	AgentFee          string `xml:"agent-fee,omitempty"`
+	Commission        string `xml:"commission,omitempty"`
+	SecurityPayment   string `xml:"security-payment,omitempty"`
+	UtilitiesIncluded string `xml:"utilities-included,omitempty"`
+	WithChildren      string `xml:"with-children,omitempty"`
+	WithPets          string `xml:"with-pets,omitempty"`
+	RoomFurniture     string `xml:"room-furniture,omitempty"`
+	KitchenFurniture  string `xml:"kitchen-furniture,omitempty"`
+	Refrigerator      string `xml:"refrigerator,omitempty"`
+	WashingMachine    string `xml:"washing-machine,omitempty"`
+	Television        string `xml:"television,omitempty"`
+	Internet          string `xml:"internet,omitempty"`
+	AirConditioner    string `xml:"air-conditioner,omitempty"`
+
+	// Commercial
+	CommercialType         []string `xml:"commercial-type,omitempty"`
+	CommercialBuildingType string   `xml:"commercial-building-type,omitempty"`
+	Purpose                []string `xml:"purpose,omitempty"`
+	PurposeWarehouse       []string `xml:"purpose-warehouse,omitempty"`
+	OfficeClass            string   `xml:"office-class,omitempty"`
+	EntranceType           string   `xml:"entrance-type,omitempty"`
+	TaxationForm           string   `xml:"taxation-form,omitempty"`
+	ElectricCapacity       string   `xml:"electric-capacity,omitempty"`
+	FloorCovering          string   `xml:"floor-covering,omitempty"`
+	WindowType             string   `xml:"window-type,omitempty"`
+	Ventilation            string   `xml:"ventilation,omitempty"`
+	FireAlarm              string   `xml:"fire-alarm,omitempty"`
+	Security               string   `xml:"security,omitempty"`
+	AccessControlSystem    string   `xml:"access-control-system,omitempty"`
+	TwentyFourSeven        string   `xml:"twenty-four-seven,omitempty"`
+	Parking                string   `xml:"parking,omitempty"`
+	ParkingPlaces          int64    `xml:"parking-places,omitempty"`
+	ParkingGuest           string   `xml:"parking-guest,omitempty"`
+	FreightElevator        string   `xml:"freight-elevator,omitempty"`
+	TruckEntrance          string   `xml:"truck-entrance,omitempty"`
+	Ramp                   string   `xml:"ramp,omitempty"`
+
+	// Houses and land plots
+	LotArea           Value  `xml:"lot-area,omitempty"`
+	LotType           string `xml:"lot-type,omitempty"`
+	HeatingSupply     string `xml:"heating-supply,omitempty"`
+	WaterSupply       string `xml:"water-supply,omitempty"`
+	SewerageSupply    string `xml:"sewerage-supply,omitempty"`
+	ElectricitySupply string `xml:"electricity-supply,omitempty"`
+	GasSupply         string `xml:"gas-supply,omitempty"`
+	Toilet            string `xml:"toilet,omitempty"`
+	Shower            string `xml:"shower,omitempty"`
+	Kitchen           string `xml:"kitchen,omitempty"`
+	Pool              string `xml:"pool,omitempty"`
+	Sauna             string `xml:"sauna,omitempty"`
+
+	// Garages and parking spaces
+	GarageType     string `xml:"garage-type,omitempty"`
+	GarageName     string `xml:"garage-name,omitempty"`
+	OwnershipType  string `xml:"ownership-type,omitempty"`
+	ParkingType    string `xml:"parking-type,omitempty"`
+	AutomaticGates string `xml:"automatic-gates,omitempty"`
+	Cctv           string `xml:"cctv,omitempty"`
+	InspectionPit  string `xml:"inspection-pit,omitempty"`
+	Cellar         string `xml:"cellar,omitempty"`
+	CarWash        string `xml:"car-wash,omitempty"`
+	AutoRepair     string `xml:"auto-repair,omitempty"`
+
+	Unknown     []UnknownElement `xml:",any"`
+	UnknownAttr []xml.Attr       `xml:",any,attr"`
+}
+
+const (
+	realtyTypeSale = "продажа"
+	realtyTypeRent = "аренда"
+
+	realtyCategoryFlat       = "квартира"
+	realtyCategoryRoom       = "комната"
+	realtyCategoryHouse      = "дом"
+	realtyCategoryLot        = "участок"
+	realtyCategoryGarage     = "гараж"
+	realtyCategoryCommercial = "коммерческая"
+
+	// realtyDateLayout is the date without time allowed in expire-date.
+	realtyDateLayout = "2006-01-02"
+)
+
+// realtyRenovation is the value of offer.Renovation.
+type realtyRenovation string
+
+const (
+	realtyRenovationNone        realtyRenovation = "без отделки"
+	realtyRenovationRough       realtyRenovation = "черновая отделка"
+	realtyRenovationPreFine     realtyRenovation = "предчистовая отделка"
+	realtyRenovationFine        realtyRenovation = "чистовая отделка"
+	realtyRenovationFinished    realtyRenovation = "с отделкой"
+	realtyRenovationTurnkey     realtyRenovation = "под ключ"
+	realtyRenovationDesigner    realtyRenovation = "дизайнерский"
+	realtyRenovationEuro        realtyRenovation = "евро"
+	realtyRenovationCosmetic    realtyRenovation = "косметический"
+	realtyRenovationGood        realtyRenovation = "хороший"
+	realtyRenovationPartial     realtyRenovation = "частичный ремонт"
+	realtyRenovationNeedsRepair realtyRenovation = "требует ремонта"
+)
+
+// realtyBuildingState is the value of offer.BuildingState.
+type realtyBuildingState string
+
+const (
+	realtyBuildingStateUnfinished realtyBuildingState = "unfinished"
+	realtyBuildingStateBuilt      realtyBuildingState = "built"
+	realtyBuildingStateHandOver   realtyBuildingState = "hand-over"
+)
+
+var (
+	realtyRenovationEnum = Enum{FoldCase: true, Values: enumValues(
+		realtyRenovationNone, realtyRenovationRough, realtyRenovationPreFine, realtyRenovationFine, realtyRenovationFinished,
+		realtyRenovationTurnkey, realtyRenovationDesigner, realtyRenovationEuro, realtyRenovationCosmetic, realtyRenovationGood,
+		realtyRenovationPartial, realtyRenovationNeedsRepair,
+	)}
+	realtyBuildingStateEnum = Enum{Values: enumValues(realtyBuildingStateUnfinished, realtyBuildingStateBuilt, realtyBuildingStateHandOver)}
+)
+
+// realtyTypes maps the accepted spellings of <type> to its canonical value.
+var realtyTypes = map[string]string{
+	"продажа": realtyTypeSale,
+	"sale":    realtyTypeSale,
+	"аренда":  realtyTypeRent,
+	"rent":    realtyTypeRent,
+}
+
+// realtyCategories maps the accepted spellings of <category> to its canonical value.
+var realtyCategories = map[string]string{
+	"квартира":       realtyCategoryFlat,
+	"flat":           realtyCategoryFlat,
+	"комната":        realtyCategoryRoom,
+	"room":           realtyCategoryRoom,
+	"дом":            realtyCategoryHouse,
+	"house":          realtyCategoryHouse,
+	"дача":           realtyCategoryHouse,
+	"коттедж":        realtyCategoryHouse,
+	"cottage":        realtyCategoryHouse,
+	"таунхаус":       realtyCategoryHouse,
+	"townhouse":      realtyCategoryHouse,
+	"часть дома":     realtyCategoryHouse,
+	"дом с участком": realtyCategoryHouse,
+	"дуплекс":        realtyCategoryHouse,
+	"duplex":         realtyCategoryHouse,
+	"участок":        realtyCategoryLot,
+	"lot":            realtyCategoryLot,
+	"гараж":          realtyCategoryGarage,
+	"garage":         realtyCategoryGarage,
+	"коммерческая":   realtyCategoryCommercial,
+	"commercial":     realtyCategoryCommercial,
+}
+
+// DealType returns the canonical offer type: "продажа", "аренда" or "" if unknown.
+func (o *Offer) DealType() string {
+	return realtyTypes[strings.ToLower(strings.TrimSpace(o.Type))]
+}
+
+// CategoryType returns the canonical offer category or "" if unknown.
+func (o *Offer) CategoryType() string {
+	return realtyCategories[strings.ToLower(strings.TrimSpace(o.Category))]
+}
+
+// IsNewBuilding reports whether the offer is a primary market lot in a new building or village.
+func (o *Offer) IsNewBuilding() bool {
+	if o.NewFlat != "" || o.YandexBuildingID != 0 || o.YandexVillageID != 0 {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(o.DealStatus)) {
+	case "первичная продажа", "первичная продажа вторички", "primary sale", "sale by assignment", "переуступка":
+		return true
+	}
+	return false
+}
+
+// The tags of the layout images of a flat.
+const (
+	realtyImageTagPlan      = "plan"
+	realtyImageTagFloorPlan = "floor-plan"
+)
+
+type RealtyImage struct {
+	Tag string `xml:"tag,attr,omitempty"`
+	URL string `xml:",chardata"`
 }
 
 type Value struct {
-	Value float32 `xml:"value"`
-	Unit  string  `xml:"unit"`
+	Value float32 `xml:"value,omitempty"`
+	Unit  string  `xml:"unit,omitempty"`
 }
 
 type vas struct {
 	Text      string `xml:",chardata"`
-	StartTime string `xml:"start-time,attr"`
-	Schedule  string `xml:"schedule,attr"`
+	StartTime string `xml:"start-time,attr,omitempty"`
+	Schedule  string `xml:"schedule,attr,omitempty"`
 }
 
+// Get downloads the feed with DefaultFetcher.
 func (f *RealtyFeed) Get(url string) (err error) {
-	resp, err := GetResponse(url)
-	if err != nil {
-		return err
-	}
+	_, err = DefaultFetcher.FetchFeed(f, url)
+	return err
+}
+
+// Marshal returns the feed as Yandex Realty XML. The default namespace is used when Xmlns is empty.
+func (f *RealtyFeed) Marshal() ([]byte, error) {
+	return marshalFeed(f.withNamespace(), map[string]bool{"description": true})
+}
 
-	defer resp.Body.Close()
+// WriteTo writes the feed as Yandex Realty XML to w.
+func (f *RealtyFeed) WriteTo(w io.Writer) (n int64, err error) {
+	return writeFeed(w, f.withNamespace(), map[string]bool{"description": true})
+}
 
-	err = statusCodeHandler(resp)
-	if err != nil {
-		return err
+func (f *RealtyFeed) withNamespace() *RealtyFeed {
+	feed := *f
+	feed.XMLName = xml.Name{Local: "realty-feed"}
+	if feed.Xmlns == "" {
+		feed.Xmlns = realtyNamespace
 	}
+	return &feed
+}
+
+// Check runs the enabled Yandex Realty rules of DefaultRules.
+func (f *RealtyFeed) Check() (results []string) {
+	return DefaultRules.Check(f)
+}
+
+// CheckWith is Check with options, e.g. checking the feed as of another date.
+func (f *RealtyFeed) CheckWith(options CheckOptions) (results []string) {
+	return DefaultRules.CheckWith(f, options)
+}
+
+var realtyRules = []Rule{
+	gateRule(FeedRule("realty/feed-size", func(f *RealtyFeed, results *Results) {
+		if len(f.Offer) < 2 {
+			results.Add("", emptyFeed)
+		}
+	})),
+	freshnessRule[*RealtyFeed](PlatformRealty),
+	lotRule("realty/ids", func(lot Offer, run *checkRun, results *Results) {
+		if lot.InternalID == "" {
+			results.Add("offer.InternalID", fmt.Sprintf("field InternalID is empty. Position: %v", results.lot))
+		}
+	}),
+	LotRule("realty/required", func(lot Offer, results *Results) {
+		if lot.Type == "" {
+			results.Add("offer.Type", fmt.Sprintf("tag 'Type'  is not found. InternalID: %v", lot.InternalID))
+		}
+
+		id := lot.InternalID
+		checkStringWithID(id, "offer", "Type", lot.Type, results)
+		checkStringWithID(id, "offer", "Category", lot.Category, results)
+		checkStringWithID(id, "offer", "CreationDate", lot.CreationDate, results)
+		checkStringWithID(id, "offer.Location", "Country", lot.Location.Country, results)
+		checkStringWithID(id, "offer.Location", "Address", lot.Location.Address, results)
+		checkStringWithID(id, "offer.SalesAgent", "Phone", lot.SalesAgent.Phone, results)
+		checkStringWithID(id, "offer.SalesAgent", "Category", lot.SalesAgent.Category, results)
+		checkZeroWithID(id, "offer.Price", "Value", lot.Price.Value, results)
+		checkStringWithID(id, "offer.Price", "Currency", lot.Price.Currency, results)
+	}),
+	LotRule("realty/enums", func(lot Offer, results *Results) {
+		id := lot.InternalID
+		checkEnumWithID(id, "offer", "Renovation", lot.Renovation, realtyRenovationEnum, results)
+		checkEnumWithID(id, "offer", "BuildingState", lot.BuildingState, realtyBuildingStateEnum, results)
+	}),
+	LotRule("realty/deal", func(lot Offer, results *Results) {
+		id := lot.InternalID
+		switch lot.DealType() {
+		case realtyTypeSale:
+			checkStringWithID(id, "offer", "DealStatus", lot.DealStatus, results)
+		case realtyTypeRent:
+			checkStringWithID(id, "offer.Price", "Period", lot.Price.Period, results)
+		default:
+			if lot.Type != "" {
+				results.Add("offer.Type", fmt.Sprintf("field offer.Type has unknown value '%v'. InternalID: %v", lot.Type, id))
+			}
+		}
+	}),
+	LotRule("realty/new-building", func(lot Offer, results *Results) {
+		if lot.IsNewBuilding() {
+			checkRealtyNewBuilding(lot, results)
+		}
+	}),
+	LotRuleAt("realty/deadline", func(lot Offer, now time.Time, results *Results) {
+		if !lot.IsNewBuilding() {
+			return
+		}
+		id := lot.InternalID
+		checkQuarterWithID(id, "offer", "ReadyQuarter", lot.ReadyQuarter, results)
+		checkDeadlineYearWithID(id, "offer", "BuiltYear", lot.BuiltYear, now.Year(), results)
 
-	AttributeLastModified := resp.Header.Get("Last-Modified")
-	if AttributeLastModified != "" {
-		lastModifiedDate, err := time.Parse(time.RFC1123, resp.Header.Get("Last-Modified"))
+		passed, ok := deadlinePassed(lot.BuiltYear, lot.ReadyQuarter, now.Year(), quarterOf(int(now.Month())))
+		if ok && lot.BuildingState == string(realtyBuildingStateUnfinished) {
+			results.Add("offer.BuildingState", fmt.Sprintf("BuildingState == unfinished for %v. InternalID: %v", passed, id))
+		}
+	}),
+	LotRuleAt("realty/expire-date", func(lot Offer, now time.Time, results *Results) {
+		if lot.ExpireDate == "" {
+			return
+		}
+		expires, err := time.Parse(time.RFC3339Nano, lot.ExpireDate)
 		if err != nil {
-			return err
+			expires, err = time.ParseInLocation(realtyDateLayout, lot.ExpireDate, now.Location())
+		}
+		switch {
+		case err != nil:
+			results.Add("offer.ExpireDate", fmt.Sprintf("field offer.ExpireDate has invalid date '%v'. InternalID: %v", lot.ExpireDate, lot.InternalID))
+		case !now.Before(expires):
+			results.Add("offer.ExpireDate", fmt.Sprintf("offer is expired since %v. InternalID: %v", lot.ExpireDate, lot.InternalID))
+		}
+	}),
+	LotRule("realty/category", func(lot Offer, results *Results) {
+		switch lot.CategoryType() {
+		case realtyCategoryFlat:
+			checkRealtyFlat(lot, results)
+		case realtyCategoryRoom:
+			checkRealtyRoom(lot, results)
+		case realtyCategoryHouse:
+			checkRealtyHouse(lot, results)
+		case realtyCategoryLot:
+			checkRealtyLot(lot, results)
+		case realtyCategoryGarage:
+			checkRealtyGarage(lot, results)
+		case realtyCategoryCommercial:
+			checkRealtyCommercial(lot, results)
+		default:
+			if lot.Category != "" {
+				results.Add("offer.Category", fmt.Sprintf("field offer.Category has unknown value '%v'. InternalID: %v", lot.Category, lot.InternalID))
+			}
+		}
+	}),
+	// Yandex Realty asks for at least three photos of flats, rooms and houses only.
+	LotRule("realty/images", func(lot Offer, results *Results) {
+		switch lot.CategoryType() {
+		case realtyCategoryFlat, realtyCategoryRoom, realtyCategoryHouse:
+		default:
+			return
+		}
+		if len(lot.Image) < 3 {
+			results.Add("offer.Image", fmt.Sprintf("field Image contains '%v' items. InternalID: %v", len(lot.Image), lot.InternalID))
+		}
+	}),
+	FeedRule("realty/building-consistency", func(f *RealtyFeed, results *Results) {
+		var groups buildingGroups
+		for idx, lot := range f.Offer {
+			key, name, ok := realtyBuilding(lot)
+			if !ok {
+				continue
+			}
+			groups.add(key, name, buildingLot{
+				id:       lot.InternalID,
+				position: idx,
+				values: map[string]string{
+					"offer.FloorsTotal":      formatBuildingInt(lot.FloorsTotal),
+					"offer.BuildingType":     lot.BuildingType,
+					"offer.BuiltYear":        realtyDeadline(lot),
+					"offer.Location.Address": strings.Join(strings.Fields(lot.Location.Address), " "),
+				},
+				latitude:  lot.Location.Latitude,
+				longitude: lot.Location.Longitude,
+			})
+		}
+		groups.check([]string{"offer.FloorsTotal", "offer.BuildingType", "offer.BuiltYear", "offer.Location.Address"}, results)
+		groups.checkCoordinates("offer.Location", results)
+	}),
+	feedRule("realty/descriptions", func(f *RealtyFeed, run *checkRun, results *Results) {
+		ids := make([]string, len(f.Offer))
+		descriptions := make([]string, len(f.Offer))
+		for idx, lot := range f.Offer {
+			ids[idx], descriptions[idx] = lot.InternalID, lot.Description
+		}
+		checkDescriptions(run.options.descriptionRule(PlatformRealty), "offer", "Description", ids, descriptions, results)
+	}),
+	FeedRule("realty/unknown", func(f *RealtyFeed, results *Results) {
+		if f.Strict {
+			f.checkUnknown(results)
+		}
+	}),
+}
+
+// realtyBuilding returns the key and name of the building of a new building offer: YandexBuildingID,
+// or BuildingName when there is no ID, with BuildingSection. ok is false for other offers.
+func realtyBuilding(lot Offer) (key string, name string, ok bool) {
+	if !lot.IsNewBuilding() {
+		return "", "", false
+	}
+	key, name = strconv.FormatInt(lot.YandexBuildingID, 10), lot.BuildingName
+	if lot.YandexBuildingID == 0 {
+		if lot.BuildingName == "" {
+			return "", "", false
 		}
-		f.LastModified = lastModifiedDate
+		key = lot.BuildingName
+	}
+	if name == "" {
+		name = key
+	}
+	if lot.BuildingSection != "" {
+		key += "/" + lot.BuildingSection
+		name += ", " + lot.BuildingSection
+	}
+	return key, name, true
+}
+
+// realtyDeadline formats the deadline and state of the building of an offer for comparison; it is empty when not set.
+func realtyDeadline(lot Offer) string {
+	if lot.BuiltYear == 0 {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("Q%d %d %s", lot.ReadyQuarter, lot.BuiltYear, lot.BuildingState))
+}
+
+// CheckUnknown reports elements and attributes of the feed and its offers that are not modelled by RealtyFeed.
+func (f *RealtyFeed) CheckUnknown() (results []string) {
+	unknown := &Results{lot: -1}
+	f.checkUnknown(unknown)
+	return unknown.messages()
+}
+
+func (f *RealtyFeed) checkUnknown(results *Results) {
+	checkUnknownWithID("", -1, "realty-feed", f.Unknown, f.UnknownAttr, results)
+	for idx, lot := range f.Offer {
+		checkUnknownWithID(lot.InternalID, idx, "offer", lot.Unknown, lot.UnknownAttr, results)
+	}
+}
+
+// DiscardUnknown drops the unmodelled elements and attributes, so Marshal writes only known fields.
+func (f *RealtyFeed) DiscardUnknown() {
+	f.Unknown, f.UnknownAttr = nil, nil
+	for i := range f.Offer {
+		f.Offer[i].Unknown, f.Offer[i].UnknownAttr = nil, nil
+	}
+}
+
+func checkRealtyNewBuilding(lot Offer, results *Results) {
+	id := lot.InternalID
+
+	if lot.BuildingName == "" {
+		checkStringWithID(id, "offer", "VillageName", lot.VillageName, results)
 	} else {
-		log.Println("Header not contains `Last-Modified`")
+		checkStringWithID(id, "offer", "BuildingName", lot.BuildingName, results)
 	}
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
+
+	if lot.YandexBuildingID == 0 {
+		checkZeroWithID(id, "offer", "YandexVillageID", int(lot.YandexVillageID), results)
+	} else {
+		checkZeroWithID(id, "offer", "YandexBuildingID", int(lot.YandexBuildingID), results)
 	}
-	err = xml.Unmarshal(responseBody, &f)
-	if err != nil {
-		return err
+
+	checkStringWithID(id, "offer", "BuildingState", lot.BuildingState, results)
+	checkZeroWithID(id, "offer", "BuiltYear", int(lot.BuiltYear), results)
+	checkZeroWithID(id, "offer", "ReadyQuarter", int(lot.ReadyQuarter), results)
+}
+
+func checkRealtyFlat(lot Offer, results *Results) {
+	id := lot.InternalID
+
+	checkStringWithID(id, "offer", "PropertyType", lot.PropertyType, results)
+	checkZeroWithID(id, "offer.Area", "Value", lot.Area.Value, results)
+	checkStringWithID(id, "offer.Area", "Unit", lot.Area.Unit, results)
+	if !isYes(lot.Studio) && !isYes(lot.OpenPlan) {
+		checkZeroWithID(id, "offer", "Rooms", int(lot.Rooms), results)
 	}
-	if time.Time.IsZero(f.LastModified) {
-		f.LastModified, err = time.Parse(time.RFC3339Nano, f.GenerationDate)
-		if err != nil {
-			return err
+	checkZeroWithID(id, "offer", "Floor", int(lot.Floor), results)
+	checkZeroWithID(id, "offer", "FloorsTotal", int(lot.FloorsTotal), results)
+
+	if lot.IsNewBuilding() {
+		checkStringWithID(id, "offer", "NewFlat", lot.NewFlat, results)
+
+		tags := make(map[string]bool)
+		for _, image := range lot.Image {
+			tags[image.Tag] = true
+		}
+		if _, ok := tags[realtyImageTagPlan]; !ok {
+			results.Add("offer.Image", fmt.Sprintf("tag 'plan' for image is not found. InternalID: %v", lot.InternalID))
 		}
+		if _, ok := tags[realtyImageTagFloorPlan]; !ok {
+			results.Add("offer.Image", fmt.Sprintf("tag 'floor-plan' for image is not found. InternalID: %v", lot.InternalID))
+		}
+	}
+
+	if lot.LivingSpace.Value == 0 && !isYes(lot.OpenPlan) {
+		results.Add("offer.LivingSpace.Value", fmt.Sprintf("field LivingSpace.Value is empty. InternalID: %v", lot.InternalID))
+	}
+	if lot.Floor > lot.FloorsTotal {
+		results.Add("offer.Floor", fmt.Sprintf("field Floor is bigger than FloorsTotal. InternalID: %v", lot.InternalID))
+	}
+	// Studios and open-plan flats have no rooms to count.
+	if !isYes(lot.Studio) && !isYes(lot.OpenPlan) && int64(len(lot.RoomSpace)) > lot.Rooms {
+		results.Add("offer.RoomSpace", fmt.Sprintf("field RoomSpace contains more values than Rooms. InternalID: %v", lot.InternalID))
 	}
-	return nil
 }
 
-func (f *RealtyFeed) Check() (results []string) {
+func checkRealtyRoom(lot Offer, results *Results) {
+	id := lot.InternalID
+
+	checkStringWithID(id, "offer", "PropertyType", lot.PropertyType, results)
+	checkZeroWithID(id, "offer.Area", "Value", lot.Area.Value, results)
+	checkZeroWithID(id, "offer", "Rooms", int(lot.Rooms), results)
+	checkZeroWithID(id, "offer", "RoomsOffered", int(lot.RoomsOffered), results)
+	checkZeroWithID(id, "offer", "Floor", int(lot.Floor), results)
+	checkZeroWithID(id, "offer", "FloorsTotal", int(lot.FloorsTotal), results)
+
+	if len(lot.RoomSpace) == 0 {
+		results.Add("offer.RoomSpace", fmt.Sprintf("field RoomSpace is empty. InternalID: %v", lot.InternalID))
+	}
+	if lot.RoomsOffered > lot.Rooms {
+		results.Add("offer.RoomsOffered", fmt.Sprintf("field RoomsOffered is bigger than Rooms. InternalID: %v", lot.InternalID))
+	}
+	if lot.Floor > lot.FloorsTotal {
+		results.Add("offer.Floor", fmt.Sprintf("field Floor is bigger than FloorsTotal. InternalID: %v", lot.InternalID))
+	}
+}
+
+func checkRealtyHouse(lot Offer, results *Results) {
+	id := lot.InternalID
+
+	checkStringWithID(id, "offer", "PropertyType", lot.PropertyType, results)
+	checkZeroWithID(id, "offer.Area", "Value", lot.Area.Value, results)
+	checkStringWithID(id, "offer.Area", "Unit", lot.Area.Unit, results)
+	checkZeroWithID(id, "offer.LotArea", "Value", lot.LotArea.Value, results)
+	checkStringWithID(id, "offer.LotArea", "Unit", lot.LotArea.Unit, results)
+}
 
-	if len(f.Offer) < 2 {
-		results = append(results, emptyFeed)
-		return results
+func checkRealtyLot(lot Offer, results *Results) {
+	id := lot.InternalID
+
+	checkZeroWithID(id, "offer.LotArea", "Value", lot.LotArea.Value, results)
+	checkStringWithID(id, "offer.LotArea", "Unit", lot.LotArea.Unit, results)
+	checkStringWithID(id, "offer", "LotType", lot.LotType, results)
+}
+
+func checkRealtyGarage(lot Offer, results *Results) {
+	id := lot.InternalID
+
+	checkStringWithID(id, "offer", "GarageType", lot.GarageType, results)
+	checkZeroWithID(id, "offer.Area", "Value", lot.Area.Value, results)
+	if lot.DealType() == realtyTypeSale {
+		checkStringWithID(id, "offer", "OwnershipType", lot.OwnershipType, results)
 	}
+}
 
-	for idx, lot := range f.Offer {
-		if lot.InternalID == "" {
-			results = append(results, fmt.Sprintf("field InternalID is empty. Position: %v", idx))
+func checkRealtyCommercial(lot Offer, results *Results) {
+	id := lot.InternalID
+
+	if len(lot.CommercialType) == 0 {
+		results.Add("offer.CommercialType", fmt.Sprintf("field offer.CommercialType is empty. InternalID: %v", id))
+	}
+
+	isLand := false
+	for _, commercialType := range lot.CommercialType {
+		if strings.EqualFold(strings.TrimSpace(commercialType), "land") {
+			isLand = true
 		}
-		tags := make(map[string]bool)
-		for _, image := range lot.Image {
-			if tags[image.Tag] {
-				continue
+	}
+
+	if isLand {
+		checkZeroWithID(id, "offer.LotArea", "Value", lot.LotArea.Value, results)
+		checkStringWithID(id, "offer.LotArea", "Unit", lot.LotArea.Unit, results)
+	} else {
+		checkZeroWithID(id, "offer.Area", "Value", lot.Area.Value, results)
+		checkStringWithID(id, "offer.Area", "Unit", lot.Area.Unit, results)
+	}
+}
+
+var realtyLotCategories = enumTable[string, LotCategory]{
+	{realtyCategoryFlat, LotCategoryFlat},
+	{realtyCategoryRoom, LotCategoryRoom},
+	{realtyCategoryHouse, LotCategoryHouse},
+	{realtyCategoryLot, LotCategoryLand},
+	{realtyCategoryGarage, LotCategoryGarage},
+	{realtyCategoryCommercial, LotCategoryCommercial},
+}
+
+var realtyDealTypes = enumTable[string, DealType]{
+	{realtyTypeSale, DealTypeSale},
+	{realtyTypeRent, DealTypeRent},
+}
+
+var realtyRenovations = enumTable[realtyRenovation, Renovation]{
+	{realtyRenovationNone, RenovationNone},
+	{realtyRenovationRough, RenovationRough},
+	{realtyRenovationPreFine, RenovationPreFine},
+	{realtyRenovationFine, RenovationFine},
+	{realtyRenovationTurnkey, RenovationTurnkey},
+	{realtyRenovationDesigner, RenovationDesigner},
+}
+
+var realtyBalconies = enumTable[string, Balcony]{
+	{"нет", BalconyNone},
+	{"балкон", BalconyBalcony},
+	{"лоджия", BalconyLoggia},
+	{"балкон и лоджия", BalconyBoth},
+	{"2 балкона", BalconyBalcony},
+	{"2 лоджии", BalconyLoggia},
+}
+
+var realtyBuildingTypes = enumTable[string, BuildingMaterial]{
+	{"кирпичный", BuildingMaterialBrick},
+	{"монолит", BuildingMaterialMonolith},
+	{"монолитный", BuildingMaterialMonolith},
+	{"кирпично-монолитный", BuildingMaterialMonolithBrick},
+	{"монолитно-кирпичный", BuildingMaterialMonolithBrick},
+	{"панельный", BuildingMaterialPanel},
+	{"блочный", BuildingMaterialBlock},
+	{"деревянный", BuildingMaterialWood},
+}
+
+// Lots returns the offers of the feed as canonical lots.
+func (f *RealtyFeed) Lots() (lots []Lot, results []string) {
+	for _, offer := range f.Offer {
+		id := offer.InternalID
+		lot := Lot{
+			Source:       PlatformRealty,
+			ID:           id,
+			NewBuilding:  offer.IsNewBuilding(),
+			Description:  offer.Description,
+			Phone:        offer.SalesAgent.Phone,
+			Address:      offer.Location.Address,
+			Latitude:     parseFloat(offer.Location.Latitude),
+			Longitude:    parseFloat(offer.Location.Longitude),
+			Price:        float64(offer.Price.Value),
+			Currency:     offer.Price.Currency,
+			Rooms:        int(offer.Rooms),
+			Studio:       isYes(offer.Studio),
+			OpenPlan:     isYes(offer.OpenPlan),
+			Area:         float64(offer.Area.Value),
+			LivingArea:   float64(offer.LivingSpace.Value),
+			KitchenArea:  float64(offer.KitchenSpace.Value),
+			Floor:        int(offer.Floor),
+			FloorsTotal:  int(offer.FloorsTotal),
+			BuildingName: offer.BuildingName,
+			Section:      offer.BuildingSection,
+			BuiltYear:    int(offer.BuiltYear),
+			ReadyQuarter: int(offer.ReadyQuarter),
+			Finished:     offer.BuildingState == string(realtyBuildingStateBuilt) || offer.BuildingState == string(realtyBuildingStateHandOver),
+		}
+		if offer.YandexBuildingID != 0 {
+			lot.ComplexID = strconv.FormatInt(offer.YandexBuildingID, 10)
+		}
+		if offer.YandexHouseID.Valid {
+			lot.BuildingID = strconv.FormatInt(offer.YandexHouseID.Int64, 10)
+		}
+		if len(offer.CeilingHeight) > 0 {
+			lot.CeilingHeight = parseFloat(offer.CeilingHeight[0])
+		}
+		if offer.CreationDate != "" {
+			created, err := time.Parse(time.RFC3339, offer.CreationDate)
+			if err != nil {
+				results = append(results, fmt.Sprintf("field offer.CreationDate value '%v' can not be mapped. InternalID: %v", offer.CreationDate, id))
 			}
-			tags[image.Tag] = true
+			lot.Created = created
 		}
 
-		if _, ok := tags["plan"]; !ok {
-			results = append(results, fmt.Sprintf("tag 'plan' for image is not found. InternalID: %v", lot.InternalID))
+		lot.Category = mapFromPlatform(realtyLotCategories, id, "offer.Category", offer.CategoryType(), &results)
+		if offer.Category != "" && offer.CategoryType() == "" {
+			results = append(results, fmt.Sprintf("field offer.Category value '%v' can not be mapped. InternalID: %v", offer.Category, id))
+		}
+		lot.Deal = mapFromPlatform(realtyDealTypes, id, "offer.Type", offer.DealType(), &results)
+		if offer.Type != "" && offer.DealType() == "" {
+			results = append(results, fmt.Sprintf("field offer.Type value '%v' can not be mapped. InternalID: %v", offer.Type, id))
 		}
+		lot.Renovation = mapFromPlatform(realtyRenovations, id, "offer.Renovation", offer.Renovation, &results)
+		lot.Balcony = mapFromPlatform(realtyBalconies, id, "offer.Balcony", offer.Balcony, &results)
+		lot.Material = mapFromPlatform(realtyBuildingTypes, id, "offer.BuildingType", offer.BuildingType, &results)
 
-		if _, ok := tags["floor-plan"]; !ok {
-			results = append(results, fmt.Sprintf("tag 'floor-plan' for image is not found. InternalID: %v", lot.InternalID))
+		for _, image := range offer.Image {
+			switch image.Tag {
+			case realtyImageTagPlan:
+				lot.PlanImages = append(lot.PlanImages, strings.TrimSpace(image.URL))
+			case realtyImageTagFloorPlan:
+				lot.FloorPlanImages = append(lot.FloorPlanImages, strings.TrimSpace(image.URL))
+			default:
+				lot.Images = append(lot.Images, strings.TrimSpace(image.URL))
+			}
 		}
+		lots = append(lots, lot)
+	}
+	return lots, results
+}
 
-		if lot.Type == "" {
-			results = append(results, fmt.Sprintf("tag 'Type'  is not found. InternalID: %v", lot.InternalID))
+// FromLots replaces the offers of the feed with the given lots. The feed is generated as of LastModified,
+// or the current time when it is zero; it is the default generation-date and creation-date of the offers.
+func (f *RealtyFeed) FromLots(lots []Lot) (results []string) {
+	now := f.LastModified
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if f.GenerationDate == "" {
+		f.GenerationDate = now.Format(time.RFC3339)
+	}
+
+	f.Offer = nil
+	for _, lot := range lots {
+		offer := Offer{
+			InternalID:      lot.ID,
+			Description:     lot.Description,
+			Rooms:           int64(lot.Rooms),
+			Floor:           int64(lot.Floor),
+			FloorsTotal:     int64(lot.FloorsTotal),
+			BuildingName:    lot.BuildingName,
+			BuildingSection: lot.Section,
+			BuiltYear:       int64(lot.BuiltYear),
+			ReadyQuarter:    int64(lot.ReadyQuarter),
+			Area:            Value{Value: float32(lot.Area), Unit: "кв. м"},
+		}
+		if lot.LivingArea != 0 {
+			offer.LivingSpace = Value{Value: float32(lot.LivingArea), Unit: "кв. м"}
+		}
+		if lot.KitchenArea != 0 {
+			offer.KitchenSpace = Value{Value: float32(lot.KitchenArea), Unit: "кв. м"}
+		}
+		if lot.CeilingHeight != 0 {
+			offer.CeilingHeight = []string{formatFloat(lot.CeilingHeight)}
+		}
+		if lot.Studio {
+			offer.Studio = "да"
+		}
+		if lot.OpenPlan {
+			offer.OpenPlan = "да"
 		}
 
-		id := lot.InternalID
+		created := lot.Created
+		if created.IsZero() {
+			created = now
+		}
+		offer.CreationDate = created.Format(time.RFC3339)
 
-		if lot.BuildingName == "" {
-			checkStringWithID(id, "offer", "VillageName", lot.VillageName, &results)
-		} else {
-			checkStringWithID(id, "offer", "BuildingName", lot.BuildingName, &results)
-		}
-
-		if lot.YandexBuildingID == 0 {
-			checkZeroWithID(id, "offer", "YandexVillageID", int(lot.YandexVillageID), &results)
-		} else {
-			checkZeroWithID(id, "offer", "YandexBuildingID", int(lot.YandexBuildingID), &results)
-		}
-
-		checkStringWithID(id, "offer", "Type", lot.Type, &results)
-		checkStringWithID(id, "offer", "PropertyType", lot.PropertyType, &results)
-		checkStringWithID(id, "offer", "CreationDate", lot.CreationDate, &results)
-		checkStringWithID(id, "offer.Location", "Country", lot.Location.Country, &results)
-		checkStringWithID(id, "offer.Location", "Address", lot.Location.Address, &results)
-		checkStringWithID(id, "offer.SalesAgent", "Phone", lot.SalesAgent.Phone, &results)
-		checkStringWithID(id, "offer.SalesAgent", "Category", lot.SalesAgent.Category, &results)
-		checkStringWithID(id, "offer", "DealStatus", lot.DealStatus, &results)
-		checkZeroWithID(id, "offer.Price", "Value", lot.Price.Value, &results)
-		checkStringWithID(id, "offer.Price", "Currency", lot.Price.Currency, &results)
-		checkZeroWithID(id, "offer.Area", "Value", lot.Area.Value, &results)
-		checkStringWithID(id, "offer.Area", "Unit", lot.Area.Unit, &results)
-		checkZeroWithID(id, "offer", "Rooms", int(lot.Rooms), &results)
-		checkStringWithID(id, "offer", "NewFlat", lot.NewFlat, &results)
-		checkZeroWithID(id, "offer", "Floor", int(lot.Floor), &results)
-		checkZeroWithID(id, "offer", "FloorsTotal", int(lot.FloorsTotal), &results)
-		checkStringWithID(id, "offer", "BuildingState", lot.BuildingState, &results)
-		checkZeroWithID(id, "offer", "BuiltYear", int(lot.BuiltYear), &results)
-		checkZeroWithID(id, "offer", "ReadyQuarter", int(lot.ReadyQuarter), &results)
-
-		if lot.LivingSpace.Value == 0 && lot.OpenPlan != "1" {
-			results = append(results, fmt.Sprintf("field LivingSpace.Value is empty. InternalID: %v", lot.InternalID))
-		}
-		if lot.BuiltYear < int64(time.Now().Year()) && lot.BuildingState == "unfinished" {
-			results = append(results, fmt.Sprintf("BuildingState == unfinished for %v. InternalID: %v", lot.BuiltYear, lot.InternalID))
-		}
-		if lot.Floor > lot.FloorsTotal {
-			results = append(results, fmt.Sprintf("field Floor is bigger than FloorsTotal. InternalID: %v", lot.InternalID))
-		}
-		if int64(len(lot.RoomSpace)) > lot.Rooms {
-			results = append(results, fmt.Sprintf("field RoomSpace contains more values than Rooms. InternalID: %v", lot.InternalID))
+		offer.Location.Country = "Россия"
+		offer.Location.Address = lot.Address
+		offer.Location.Latitude = formatFloat(lot.Latitude)
+		offer.Location.Longitude = formatFloat(lot.Longitude)
+		offer.SalesAgent.Phone = lot.Phone
+		offer.Price.Value = float32(lot.Price)
+		offer.Price.Currency = "RUB"
+		if lot.Currency != "" && !strings.EqualFold(lot.Currency, "RUR") {
+			offer.Price.Currency = strings.ToUpper(lot.Currency)
 		}
-		if len(lot.Image) < 3 {
-			results = append(results, fmt.Sprintf("field Image contains '%v' items. InternalID: %v", len(lot.Image), lot.InternalID))
+
+		offer.Type = mapToPlatform(realtyDealTypes, PlatformRealty, lot.ID, "Deal", lot.Deal, &results)
+		offer.Category = mapToPlatform(realtyLotCategories, PlatformRealty, lot.ID, "Category", lot.Category, &results)
+		offer.Renovation = mapToPlatform(realtyRenovations, PlatformRealty, lot.ID, "Renovation", lot.Renovation, &results)
+		offer.Balcony = mapToPlatform(realtyBalconies, PlatformRealty, lot.ID, "Balcony", lot.Balcony, &results)
+		offer.BuildingType = mapToPlatform(realtyBuildingTypes, PlatformRealty, lot.ID, "Material", lot.Material, &results)
+
+		switch lot.Category {
+		case LotCategoryFlat, LotCategoryRoom, LotCategoryHouse, LotCategoryLand:
+			offer.PropertyType = "жилая"
+		}
+
+		if lot.NewBuilding {
+			offer.SalesAgent.Category = "застройщик"
+			if lot.Deal == DealTypeSale {
+				offer.DealStatus = "первичная продажа"
+			}
+			if lot.Category == LotCategoryFlat {
+				offer.NewFlat = "да"
+			}
+			offer.BuildingState = string(realtyBuildingStateUnfinished)
+			if lot.Finished {
+				offer.BuildingState = string(realtyBuildingStateHandOver)
+			}
+		} else if lot.Deal == DealTypeSale {
+			offer.DealStatus = "прямая продажа"
+		}
+
+		if complexID := copyCatalogID(lot, PlatformRealty, "ComplexID", lot.ComplexID, &results); complexID != "" {
+			offer.YandexBuildingID, _ = strconv.ParseInt(complexID, 10, 64)
+		}
+		if buildingID := copyCatalogID(lot, PlatformRealty, "BuildingID", lot.BuildingID, &results); buildingID != "" {
+			houseID, _ := strconv.ParseInt(buildingID, 10, 64)
+			offer.YandexHouseID = CustomInt64{Int64: houseID, Valid: true}
+		}
+
+		for _, url := range lot.Images {
+			offer.Image = append(offer.Image, RealtyImage{URL: url})
+		}
+		for _, url := range lot.PlanImages {
+			offer.Image = append(offer.Image, RealtyImage{Tag: realtyImageTagPlan, URL: url})
+		}
+		for _, url := range lot.FloorPlanImages {
+			offer.Image = append(offer.Image, RealtyImage{Tag: realtyImageTagFloorPlan, URL: url})
 		}
+		f.Offer = append(f.Offer, offer)
 	}
 	return results
 }
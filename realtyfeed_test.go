@@ -0,0 +1,247 @@
+package price_placements_feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRealtyExpireDate(t *testing.T) {
+	feed := &RealtyFeed{Offer: []Offer{
+		{InternalID: "no-date"},
+		{InternalID: "future", ExpireDate: "2024-06-01T00:00:00+03:00"},
+		{InternalID: "past", ExpireDate: "2024-05-15T12:00:00Z"},
+		{InternalID: "date-only", ExpireDate: "2024-05-14"},
+		{InternalID: "invalid", ExpireDate: "15.05.2024"},
+	}}
+
+	results := DefaultRules.Only("realty/expire-date").CheckWith(feed, CheckOptions{Now: testClock})
+	want := []string{
+		"offer is expired since 2024-05-15T12:00:00Z. InternalID: past",
+		"offer is expired since 2024-05-14. InternalID: date-only",
+		"field offer.ExpireDate has invalid date '15.05.2024'. InternalID: invalid",
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("CheckWith() = %q, want %q", results, want)
+	}
+
+	earlier := func() time.Time { return time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC) }
+	if results := DefaultRules.Only("realty/expire-date").CheckWith(feed, CheckOptions{Now: earlier}); len(results) != 1 {
+		t.Errorf("CheckWith() as of May 1 = %q", results)
+	}
+}
+
+func TestRealtyCheckWithDate(t *testing.T) {
+	feed := &RealtyFeed{Offer: []Offer{
+		{InternalID: "1", NewFlat: "да", BuildingState: "unfinished", BuiltYear: 2024, ReadyQuarter: 2},
+		{InternalID: "2", NewFlat: "да", BuildingState: "unfinished", BuiltYear: 2025, ReadyQuarter: 1},
+	}}
+	unfinished := func(results []string) (lots []string) {
+		for _, result := range results {
+			if strings.HasPrefix(result, "BuildingState == unfinished") {
+				lots = append(lots, result)
+			}
+		}
+		return lots
+	}
+
+	if got := unfinished(feed.CheckWith(CheckOptions{Now: testClock})); got != nil {
+		t.Errorf("deadline reported before it has passed: %q", got)
+	}
+	july := func() time.Time { return time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC) }
+	want := []string{"BuildingState == unfinished for Q2 2024. InternalID: 1"}
+	if got := unfinished(feed.CheckWith(CheckOptions{Now: july})); !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckWith() as of July = %q, want %q", got, want)
+	}
+}
+
+func TestBuildRealtyFeedUsesCheckTime(t *testing.T) {
+	lots := []Lot{
+		{ID: "1", Category: LotCategoryFlat, Deal: DealTypeSale, Price: 1e7, Area: 40, Rooms: 1, Floor: 2, FloorsTotal: 9},
+		{ID: "2", Category: LotCategoryFlat, Deal: DealTypeSale, Price: 1e7, Area: 40, Rooms: 1, Floor: 3, FloorsTotal: 9,
+			Created: time.Date(2024, time.January, 10, 0, 0, 0, 0, time.UTC)},
+	}
+	var buf bytes.Buffer
+	results, err := BuildFeedWith(PlatformRealty, lots, &buf, CheckOptions{Now: testClock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if strings.HasPrefix(result, "feed ") {
+			t.Errorf("built feed has a freshness issue: %s", result)
+		}
+	}
+
+	var feed RealtyFeed
+	if err := xml.Unmarshal(buf.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if feed.GenerationDate != "2024-05-15T12:00:00Z" {
+		t.Errorf("generation-date = %s", feed.GenerationDate)
+	}
+	if feed.Offer[0].CreationDate != "2024-05-15T12:00:00Z" || feed.Offer[1].CreationDate != "2024-01-10T00:00:00Z" {
+		t.Errorf("creation-date = %s, %s", feed.Offer[0].CreationDate, feed.Offer[1].CreationDate)
+	}
+}
+
+func TestRealtyBuildingConsistencyComparesDeadlines(t *testing.T) {
+	feed := &RealtyFeed{}
+	for i := 0; i < 4; i++ {
+		feed.Offer = append(feed.Offer, Offer{
+			InternalID: strconv.Itoa(i), NewFlat: "да", YandexBuildingID: 100, BuildingName: "ЖК Парк",
+			BuildingState: "unfinished", BuiltYear: 2025, ReadyQuarter: 4, FloorsTotal: 9,
+		})
+	}
+	feed.Offer[1].ReadyQuarter = 7
+	feed.Offer[3].ReadyQuarter = 2
+
+	var got []string
+	for _, issue := range DefaultRules.Only("realty/deadline", "realty/building-consistency").CheckIssues(feed, CheckOptions{Now: testClock}) {
+		got = append(got, issue.Rule+" "+issue.LotID+" "+issue.Field)
+	}
+	want := []string{
+		"realty/deadline 1 offer.ReadyQuarter",
+		"realty/building-consistency 1 offer.BuiltYear",
+		"realty/building-consistency 3 offer.BuiltYear",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %q, want %q", got, want)
+	}
+}
+
+func TestRealtyBuildingLots(t *testing.T) {
+	feed := &RealtyFeed{Offer: []Offer{
+		{InternalID: "1", BuildingName: "ЖК Парк", BuildingSection: "Секция 2"},
+	}}
+	lots, _ := feed.Lots()
+	if lots[0].ComplexName != "" || lots[0].BuildingName != "ЖК Парк" || lots[0].Section != "Секция 2" {
+		t.Errorf("lot = %q, %q, %q", lots[0].ComplexName, lots[0].BuildingName, lots[0].Section)
+	}
+
+	// A Realty to Cian conversion names the house after the building, not the section.
+	cian := &CianFeed{}
+	cian.FromLots(lots)
+	house := cian.Object[0].JKSchema.House
+	if house.Name != "ЖК Парк" || house.Flat.SectionNumber != "Секция 2" {
+		t.Errorf("house = %q, section = %q", house.Name, house.Flat.SectionNumber)
+	}
+}
+
+func TestRealtyBuildingFromLots(t *testing.T) {
+	feed := &RealtyFeed{}
+	feed.FromLots([]Lot{
+		{ID: "1", ComplexName: "ЖК Парк", BuildingName: "Корпус 1", Section: "2"},
+		{ID: "2", ComplexName: "ЖК Парк", BuildingName: "Корпус 1"},
+		{ID: "3", ComplexName: "ЖК Парк"},
+	})
+
+	var got []string
+	for _, offer := range feed.Offer {
+		got = append(got, offer.BuildingName+"/"+offer.BuildingSection)
+	}
+	want := []string{"Корпус 1/2", "Корпус 1/", "/"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("building-name/building-section = %q, want %q", got, want)
+	}
+
+	lots, _ := feed.Lots()
+	for idx, lot := range lots {
+		if lot.BuildingName != feed.Offer[idx].BuildingName || lot.ComplexName != "" {
+			t.Errorf("lot %s = %q, %q", lot.ID, lot.ComplexName, lot.BuildingName)
+		}
+	}
+}
+
+func TestRealtyPlanImages(t *testing.T) {
+	lot := Lot{
+		ID: "1", Category: LotCategoryFlat, Deal: DealTypeSale, NewBuilding: true,
+		Images:          []string{"https://example.com/1.jpg"},
+		PlanImages:      []string{"https://example.com/plan.jpg"},
+		FloorPlanImages: []string{"https://example.com/floor.jpg"},
+	}
+	feed := &RealtyFeed{}
+	feed.FromLots([]Lot{lot})
+
+	want := []RealtyImage{
+		{URL: "https://example.com/1.jpg"},
+		{Tag: "plan", URL: "https://example.com/plan.jpg"},
+		{Tag: "floor-plan", URL: "https://example.com/floor.jpg"},
+	}
+	if !reflect.DeepEqual(feed.Offer[0].Image, want) {
+		t.Errorf("images = %+v, want %+v", feed.Offer[0].Image, want)
+	}
+	for _, issue := range DefaultRules.Only("realty/category").CheckIssues(feed, CheckOptions{}) {
+		if issue.Field == "offer.Image" {
+			t.Errorf("issue = %s", issue.Message)
+		}
+	}
+
+	lots, _ := feed.Lots()
+	if !reflect.DeepEqual(lots[0].Images, lot.Images) || !reflect.DeepEqual(lots[0].PlanImages, lot.PlanImages) ||
+		!reflect.DeepEqual(lots[0].FloorPlanImages, lot.FloorPlanImages) {
+		t.Errorf("lot images = %q, %q, %q", lots[0].Images, lots[0].PlanImages, lots[0].FloorPlanImages)
+	}
+}
+
+func TestRealtyRoomSpace(t *testing.T) {
+	rooms := []Value{{Value: 18, Unit: "кв. м"}, {Value: 12, Unit: "кв. м"}}
+	feed := &RealtyFeed{Offer: []Offer{
+		{InternalID: "studio", Category: "квартира", Studio: "да", RoomSpace: rooms[:1]},
+		{InternalID: "open-plan", Category: "квартира", Rooms: 1, OpenPlan: "1", RoomSpace: rooms},
+		{InternalID: "one-room", Category: "квартира", Rooms: 1, RoomSpace: rooms},
+		{InternalID: "two-rooms", Category: "квартира", Rooms: 2, RoomSpace: rooms},
+	}}
+
+	var got []string
+	for _, issue := range DefaultRules.Only("realty/category").CheckIssues(feed, CheckOptions{}) {
+		if issue.Field == "offer.RoomSpace" {
+			got = append(got, issue.LotID)
+		}
+	}
+	if want := []string{"one-room"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RoomSpace issues for %q, want %q", got, want)
+	}
+}
+
+func TestRealtyRoomsRequired(t *testing.T) {
+	feed := &RealtyFeed{Offer: []Offer{
+		{InternalID: "studio", Category: "квартира", Studio: "да"},
+		{InternalID: "open-plan", Category: "квартира", OpenPlan: "true"},
+		{InternalID: "not-studio", Category: "квартира", Studio: "нет"},
+		{InternalID: "no-studio", Category: "квартира"},
+		{InternalID: "rooms", Category: "квартира", Studio: "false", Rooms: 2},
+	}}
+
+	var got []string
+	for _, issue := range DefaultRules.Only("realty/category").CheckIssues(feed, CheckOptions{}) {
+		if issue.Field == "offer.Rooms" {
+			got = append(got, issue.LotID)
+		}
+	}
+	if want := []string{"not-studio", "no-studio"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Rooms issues for %q, want %q", got, want)
+	}
+}
+
+func TestRealtyImagesOfResidentialOffers(t *testing.T) {
+	feed := &RealtyFeed{Offer: []Offer{
+		{InternalID: "flat", Category: "квартира"},
+		{InternalID: "room", Category: "комната"},
+		{InternalID: "house", Category: "дом"},
+		{InternalID: "lot", Category: "участок"},
+		{InternalID: "garage", Category: "гараж"},
+		{InternalID: "commercial", Category: "коммерческая"},
+	}}
+
+	var got []string
+	for _, issue := range DefaultRules.Only("realty/images").CheckIssues(feed, CheckOptions{}) {
+		got = append(got, issue.LotID)
+	}
+	if want := []string{"flat", "room", "house"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("images issues for %q, want %q", got, want)
+	}
+}
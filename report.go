@@ -0,0 +1,485 @@
+package price_placements_feeds
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report holds the check results of a feed, ready to be written in one of the report formats.
+type Report struct {
+	Platform  Platform  `json:"platform"`
+	Source    string    `json:"source,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+	LotCount  int       `json:"lot_count"`
+	// LotsWithIssues is the number of distinct lots with at least one issue.
+	LotsWithIssues int `json:"lots_with_issues"`
+	// Summary groups all issues by rule and field, including the ones dropped by Limit.
+	Summary []IssueSummary `json:"summary"`
+	Issues  []Issue        `json:"issues"`
+	// Omitted is the number of issues dropped by Limit.
+	Omitted int `json:"omitted,omitempty"`
+	// Suppressed are the issues accepted by a baseline. They are not part of Issues and Summary.
+	Suppressed []SuppressedIssue `json:"suppressed,omitempty"`
+}
+
+// reportExamples is the number of example lots kept per summary group.
+const reportExamples = 5
+
+type ReportFormat string
+
+const (
+	ReportJSON  ReportFormat = "json"
+	ReportCSV   ReportFormat = "csv"
+	ReportHTML  ReportFormat = "html"
+	ReportJUnit ReportFormat = "junit"
+	ReportSARIF ReportFormat = "sarif"
+	ReportText  ReportFormat = "text"
+)
+
+// NewReport makes a report of the issues returned by CheckIssues. Source is the feed URL or file name
+// and checkedAt the time of the check, which baselines expire as of.
+func NewReport(platform Platform, source string, lotCount int, issues []Issue, checkedAt time.Time) *Report {
+	report := &Report{
+		Platform:  platform,
+		Source:    source,
+		CheckedAt: checkedAt,
+		LotCount:  lotCount,
+		Issues:    issues,
+	}
+	report.summarize()
+	return report
+}
+
+// ApplyBaseline moves the issues accepted by the baseline to Suppressed. It must be called before Limit.
+func (r *Report) ApplyBaseline(baseline *Baseline) {
+	var suppressed []SuppressedIssue
+	r.Issues, suppressed = baseline.Apply(r.Issues, r.CheckedAt)
+	r.Suppressed = append(r.Suppressed, suppressed...)
+	r.summarize()
+}
+
+func (r *Report) summarize() {
+	lots := make(map[string]bool)
+	for _, issue := range r.Issues {
+		if lot := issue.Lot(); lot != "" {
+			lots[lot] = true
+		}
+	}
+	r.LotsWithIssues = len(lots)
+	r.Summary = SummarizeIssues(r.Issues, r.LotCount, reportExamples)
+}
+
+// Limit keeps at most perGroup issues of every rule and field, so a systematic mistake
+// doesn't produce thousands of identical lines. The summary still counts all issues.
+// A perGroup of zero or less keeps all issues.
+func (r *Report) Limit(perGroup int) {
+	if perGroup <= 0 {
+		return
+	}
+	kept := make(map[string]int)
+	issues := r.Issues[:0]
+	for _, issue := range r.Issues {
+		key := issue.Rule + "\x00" + issue.Field
+		if kept[key] >= perGroup {
+			r.Omitted++
+			continue
+		}
+		kept[key]++
+		issues = append(issues, issue)
+	}
+	r.Issues = issues
+}
+
+// CheckReport checks the feed and makes a report of the results.
+func CheckReport(platform Platform, source string, feed Feed) *Report {
+	return CheckReportWith(platform, source, feed, CheckOptions{})
+}
+
+// CheckReportWith checks the feed with DefaultRules and the options and makes a report of the results.
+// The report is dated with the time of the options, so that a baseline expires as of that time too.
+func CheckReportWith(platform Platform, source string, feed Feed, options CheckOptions) *Report {
+	checkedAt := options.now()
+	options.Now = func() time.Time { return checkedAt }
+	issues := DefaultRules.CheckIssues(feed, options)
+	lots, _ := feed.Lots()
+	return NewReport(platform, source, len(lots), issues, checkedAt)
+}
+
+// Write writes the report in the given format.
+func (r *Report) Write(w io.Writer, format ReportFormat) error {
+	switch format {
+	case ReportJSON:
+		return r.WriteJSON(w)
+	case ReportCSV:
+		return r.WriteCSV(w)
+	case ReportHTML:
+		return r.WriteHTML(w)
+	case ReportJUnit:
+		return r.WriteJUnit(w)
+	case ReportSARIF:
+		return r.WriteSARIF(w)
+	case ReportText:
+		return r.WriteText(w)
+	}
+	return fmt.Errorf("unknown report format: %s", format)
+}
+
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteCSV writes one row per issue, new issues first and then the ones suppressed by the baseline.
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"lot", "rule", "field", "message", "status", "reason"}); err != nil {
+		return err
+	}
+	for _, issue := range r.Issues {
+		if err := writer.Write([]string{issue.Lot(), issue.Rule, issue.Field, issue.Message, "new", ""}); err != nil {
+			return err
+		}
+	}
+	for _, issue := range r.Suppressed {
+		if err := writer.Write([]string{issue.Lot(), issue.Rule, issue.Field, issue.Message, "baselined", issue.Reason}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteText writes the summary, one line per rule and field, e.g. for an e-mail.
+func (r *Report) WriteText(w io.Writer) error {
+	header := fmt.Sprintf("%s feed", r.Platform)
+	if r.Source != "" {
+		header += " " + r.Source
+	}
+	if _, err := fmt.Fprintf(w, "%s: %d lots, %d issues\n", header, r.LotCount, r.issueCount()); err != nil {
+		return err
+	}
+	for _, summary := range r.Summary {
+		if _, err := fmt.Fprintf(w, "- %s\n", summary); err != nil {
+			return err
+		}
+	}
+	if len(r.Suppressed) > 0 {
+		if _, err := fmt.Fprintf(w, "%d known issues are suppressed by the baseline\n", len(r.Suppressed)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// issueCount is the number of issues including the omitted ones.
+func (r *Report) issueCount() int {
+	return len(r.Issues) + r.Omitted
+}
+
+// issueGroup is a set of issues of a lot.
+type issueGroup struct {
+	Lot    string
+	Issues []Issue
+}
+
+// byLot groups the issues by lot in the order of the feed. Feed issues are grouped under an empty lot.
+func (r *Report) byLot() (groups []*issueGroup) {
+	index := make(map[string]*issueGroup)
+	for _, issue := range r.Issues {
+		lot := issue.Lot()
+		group, ok := index[lot]
+		if !ok {
+			group = &issueGroup{Lot: lot}
+			index[lot] = group
+			groups = append(groups, group)
+		}
+		group.Issues = append(group.Issues, issue)
+	}
+	return groups
+}
+
+var reportHTMLTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>{{.Report.Platform}} feed report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.2em; margin-top: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+td.count { text-align: right; white-space: nowrap; }
+.muted { color: #777; }
+details { margin: 4px 0; }
+summary { cursor: pointer; }
+ul { margin: 4px 0; }
+</style>
+</head>
+<body>
+<h1>{{.Report.Platform}} feed report</h1>
+<p>
+{{- if .Report.Source}}{{.Report.Source}}<br>{{end}}
+Checked at {{.Report.CheckedAt.Format "2006-01-02 15:04:05 MST"}}<br>
+Lots: {{.Report.LotCount}}, new issues: {{.IssueCount}}, lots with new issues: {{.Report.LotsWithIssues}}
+{{- if .Report.Suppressed}}, suppressed by the baseline: {{len .Report.Suppressed}}{{end}}
+</p>
+{{- if not .Report.Summary}}
+<p>No new issues found.</p>
+{{- else}}
+<h2>By rule</h2>
+<table>
+<tr><th>Rule</th><th>Field</th><th>Issues</th><th>Lots</th><th>% of lots</th><th>Example</th></tr>
+{{- range .Report.Summary}}
+<tr><td>{{.Rule}}</td><td>{{.Field}}</td><td class="count">{{.Count}}</td><td class="count">{{.Lots}}</td>
+<td class="count">{{if .LotPercent}}{{.LotPercent}}%{{end}}</td>
+<td>{{.Message}}{{if .Examples}}<br><span class="muted">Lots: {{range $i, $lot := .Examples}}{{if $i}}, {{end}}{{$lot}}{{end}}</span>{{end}}</td></tr>
+{{- end}}
+</table>
+<h2>By lot</h2>
+{{- if .Report.Omitted}}
+<p class="muted">{{.Report.Omitted}} repeated issues are omitted.</p>
+{{- end}}
+{{- range .ByLot}}
+<details><summary>{{if .Lot}}{{.Lot}}{{else}}<span class="muted">feed</span>{{end}} ({{len .Issues}})</summary><ul>
+{{- range .Issues}}<li>{{.Message}}</li>{{end}}
+</ul></details>
+{{- end}}
+{{- end}}
+{{- if .Report.Suppressed}}
+<h2 class="muted">Suppressed by the baseline</h2>
+<details class="muted"><summary>{{len .Report.Suppressed}} known issues</summary>
+<table>
+<tr><th>Lot</th><th>Issue</th><th>Reason</th><th>Expires</th></tr>
+{{- range .Report.Suppressed}}
+<tr><td>{{.Lot}}</td><td>{{.Message}}</td><td>{{.Reason}}</td><td>{{.Expires}}</td></tr>
+{{- end}}
+</table>
+</details>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes a self-contained HTML page with the issues grouped by rule and by lot.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportHTMLTemplate.Execute(w, struct {
+		Report     *Report
+		ByLot      []*issueGroup
+		IssueCount int
+	}{r, r.byLot(), r.issueCount()})
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes a JUnit XML test suite with a test case per lot with issues and one for the feed itself.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitTestSuite{
+		Name:      fmt.Sprintf("%s %s", r.Platform, r.Source),
+		Timestamp: r.CheckedAt.Format("2006-01-02T15:04:05"),
+	}
+
+	groups := r.byLot()
+	hasFeedGroup := false
+	for _, group := range groups {
+		hasFeedGroup = hasFeedGroup || group.Lot == ""
+	}
+	if !hasFeedGroup {
+		groups = append([]*issueGroup{{}}, groups...)
+	}
+	for _, group := range groups {
+		testCase := junitTestCase{Name: "lot " + group.Lot, ClassName: string(r.Platform)}
+		if group.Lot == "" {
+			testCase.Name = "feed"
+		}
+		if len(group.Issues) > 0 {
+			var text string
+			for _, issue := range group.Issues {
+				text += issue.Message + "\n"
+			}
+			testCase.Failure = &junitFailure{
+				Message: strconv.Itoa(len(group.Issues)) + " issues",
+				Type:    group.Issues[0].Rule,
+				Text:    text,
+			}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Tests = len(suite.Cases)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name  string      `json:"name"`
+			Rules []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID       string             `json:"ruleId"`
+	Level        string             `json:"level"`
+	Message      sarifMessage       `json:"message"`
+	Locations    []sarifLocation    `json:"locations,omitempty"`
+	Suppressions []sarifSuppression `json:"suppressions,omitempty"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation struct {
+		URI string `json:"uri"`
+	} `json:"artifactLocation"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName,omitempty"`
+	Kind               string `json:"kind"`
+}
+
+// WriteSARIF writes the issues as a SARIF 2.1.0 log. Lots and fields are reported as logical locations
+// and baselined issues as suppressed results.
+func (r *Report) WriteSARIF(w io.Writer) error {
+	run := sarifRun{Results: []sarifResult{}}
+	run.Tool.Driver.Name = "price-placements"
+	run.Tool.Driver.Rules = []sarifRule{}
+
+	rules := make(map[string]bool)
+	addResult := func(issue Issue, suppressions []sarifSuppression) {
+		if !rules[issue.Rule] {
+			rules[issue.Rule] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: issue.Rule, ShortDescription: sarifMessage{Text: issue.Rule}})
+		}
+
+		var location sarifLocation
+		if uri := sarifURI(r.Source); uri != "" {
+			location.PhysicalLocation = &sarifPhysicalLocation{}
+			location.PhysicalLocation.ArtifactLocation.URI = uri
+		}
+		if lot := issue.Lot(); lot != "" {
+			location.LogicalLocations = append(location.LogicalLocations, sarifLogicalLocation{Name: lot, Kind: "object"})
+		}
+		if issue.Field != "" {
+			location.LogicalLocations = append(location.LogicalLocations, sarifLogicalLocation{
+				Name:               issue.Field,
+				FullyQualifiedName: issue.Lot() + "/" + issue.Field,
+				Kind:               "member",
+			})
+		}
+		result := sarifResult{
+			RuleID:       issue.Rule,
+			Level:        "error",
+			Message:      sarifMessage{Text: issue.Message},
+			Suppressions: suppressions,
+		}
+		if location.PhysicalLocation != nil || len(location.LogicalLocations) != 0 {
+			result.Locations = []sarifLocation{location}
+		}
+		run.Results = append(run.Results, result)
+	}
+	for _, issue := range r.Issues {
+		addResult(issue, nil)
+	}
+	for _, issue := range r.Suppressed {
+		addResult(issue.Issue, []sarifSuppression{{Kind: "external", Justification: issue.Reason}})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifURI makes the artifact URI of a report source. URLs are kept with their password hidden,
+// absolute file paths become file URIs and relative ones relative references. An empty source has no URI.
+func sarifURI(source string) string {
+	if source == "" {
+		return ""
+	}
+	if u, err := url.Parse(source); err == nil && len(u.Scheme) > 1 && u.Host != "" {
+		return redactURL(source)
+	}
+	path := filepath.ToSlash(source)
+	if filepath.IsAbs(source) {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		return (&url.URL{Scheme: "file", Path: path}).String()
+	}
+	return (&url.URL{Path: path}).String()
+}
@@ -0,0 +1,196 @@
+package price_placements_feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFetchLastModified(t *testing.T) {
+	data, err := xml.Marshal(testAvitoFeed(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		header string
+		want   time.Time
+	}{
+		{"Wed, 15 May 2024 09:00:00 GMT", time.Date(2024, time.May, 15, 9, 0, 0, 0, time.UTC)},
+		{"Wednesday, 15-May-24 09:00:00 GMT", time.Date(2024, time.May, 15, 9, 0, 0, 0, time.UTC)},
+		{"2024-05-15", time.Time{}},
+		{"", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.header != "" {
+					w.Header().Set("Last-Modified", tt.header)
+				}
+				w.Write(data)
+			}))
+			defer server.Close()
+
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			feed := &AvitoFeed{}
+			result, err := (&Fetcher{}).FetchFeed(feed, server.URL)
+			if err != nil {
+				t.Fatalf("FetchFeed() = %v", err)
+			}
+			if !result.LastModified.Equal(tt.want) || !feed.LastModified.Equal(tt.want) {
+				t.Errorf("LastModified = %s, want %s", result.LastModified, tt.want)
+			}
+			if logs.Len() > 0 {
+				t.Errorf("FetchFeed() logged %q", logs.String())
+			}
+		})
+	}
+}
+
+func TestFetchRetriesAndFallsBackToMirrors(t *testing.T) {
+	data, err := xml.Marshal(testAvitoFeed(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		switch r.URL.Path {
+		case "/flaky.xml":
+			if len(requests) < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+		case "/down.xml":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		case "/missing.xml":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "/broken.xml":
+			w.Write([]byte("<Ads><Ad>"))
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	fetcher := &Fetcher{Retry: RetryPolicy{MaxAttempts: 3, Backoff: Duration{time.Millisecond}}}
+	tests := []struct {
+		name     string
+		paths    []string
+		requests []string
+		url      string
+		wantErr  bool
+	}{
+		{"retried", []string{"/flaky.xml"}, []string{"/flaky.xml", "/flaky.xml", "/flaky.xml"}, "/flaky.xml", false},
+		{"mirror", []string{"/down.xml", "/avito.xml"}, []string{"/down.xml", "/down.xml", "/down.xml", "/avito.xml"}, "/avito.xml", false},
+		{"not found is not retried", []string{"/missing.xml", "/avito.xml"}, []string{"/missing.xml", "/avito.xml"}, "/avito.xml", false},
+		{"decode error skips mirrors", []string{"/broken.xml", "/avito.xml"}, []string{"/broken.xml"}, "/broken.xml", true},
+		{"all down", []string{"/down.xml", "/missing.xml"}, []string{"/down.xml", "/down.xml", "/down.xml", "/missing.xml"}, "/missing.xml", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = nil
+			var urls []string
+			for _, path := range tt.paths {
+				urls = append(urls, server.URL+path)
+			}
+			result, err := fetcher.FetchFeed(&AvitoFeed{}, urls...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchFeed() = %v", err)
+			}
+			if !reflect.DeepEqual(requests, tt.requests) || result.Attempts != len(tt.requests) {
+				t.Errorf("requests = %q, attempts = %d, want %q", requests, result.Attempts, tt.requests)
+			}
+			if result.URL != server.URL+tt.url {
+				t.Errorf("URL = %s, want %s", result.URL, server.URL+tt.url)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 4, Backoff: Duration{time.Second}, MaxBackoff: Duration{3 * time.Second}}
+	unavailable := &HTTPStatusError{StatusCode: http.StatusServiceUnavailable}
+
+	for attempt, max := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 3 * time.Second} {
+		delay, ok := policy.retryDelay(attempt, unavailable)
+		if !ok || delay < max/2 || delay > max {
+			t.Errorf("attempt %d: delay %s, %v, want up to %s", attempt, delay, ok, max)
+		}
+	}
+	if _, ok := policy.retryDelay(4, unavailable); ok {
+		t.Error("retried after MaxAttempts")
+	}
+	if _, ok := policy.retryDelay(1, &HTTPStatusError{StatusCode: http.StatusForbidden}); ok {
+		t.Error("403 is retried")
+	}
+	if _, ok := policy.retryDelay(1, &FetchError{}); !ok {
+		t.Error("network error is not retried")
+	}
+
+	// The doubling of the backoff doesn't overflow to a short delay: (2^40+1)<<24 wraps to 2^24ns.
+	long := RetryPolicy{MaxAttempts: 1000, Backoff: Duration{1<<40 + 1}, MaxBackoff: Duration{time.Hour}}
+	for _, attempt := range []int{25, 34, 64, 65, 999} {
+		if delay, ok := long.retryDelay(attempt, unavailable); !ok || delay < 30*time.Minute || delay > time.Hour {
+			t.Errorf("attempt %d: delay %s, %v", attempt, delay, ok)
+		}
+	}
+
+	limited := &HTTPStatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 2 * time.Second}
+	if delay, _ := policy.retryDelay(1, limited); delay != 2*time.Second {
+		t.Errorf("Retry-After delay = %s", delay)
+	}
+	limited.RetryAfter = time.Minute
+	if delay, _ := policy.retryDelay(1, limited); delay != 3*time.Second {
+		t.Errorf("Retry-After delay over MaxBackoff = %s", delay)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"-1":                            0,
+		"Wed, 15 May 2024 12:00:30 GMT": 30 * time.Second,
+		"Wed, 15 May 2024 11:00:00 GMT": 0,
+		"soon":                          0,
+	}
+	for header, want := range tests {
+		if got := parseRetryAfter(header, testNow); got != want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", header, got, want)
+		}
+	}
+}
+
+func TestGetResponseStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "feed is being generated", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	response, err := GetResponse(server.URL)
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("GetResponse() error = %v", err)
+	}
+	if response == nil || response.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("GetResponse() response = %+v", response)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil || string(body) != "feed is being generated\n" {
+		t.Errorf("body = %q, %v", body, err)
+	}
+}
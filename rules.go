@@ -0,0 +1,418 @@
+package price_placements_feeds
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Rule is a named check. Lot rules run for every lot of the feeds they apply to and feed rules
+// once per feed. Rules are made with LotRule and FeedRule, or LotRuleAt and FeedRuleAt for date checks,
+// and registered in a RuleSet.
+// Built-in rules are named "<platform>/<check>", e.g. "avito/images".
+type Rule struct {
+	Name string
+
+	lot  func(lot any, run *checkRun, results *Results)
+	feed func(feed Feed, run *checkRun, results *Results)
+	// accepts reports whether a lot rule applies to the lot.
+	accepts func(lot any) bool
+	// gate rules run before the others and stop the check when they report an issue.
+	gate bool
+}
+
+// FeedLot is a platform-specific lot: an Avito Ad, a Cian Object, a Yandex Realty Offer or a Domclick Flat.
+type FeedLot interface {
+	Ad | Object | Offer | Flat
+}
+
+// LotRule makes a rule that checks every lot of type L, e.g. every Ad of Avito feeds.
+func LotRule[L FeedLot](name string, check func(lot L, results *Results)) Rule {
+	return lotRule(name, func(lot L, run *checkRun, results *Results) {
+		check(lot, results)
+	})
+}
+
+// LotRuleAt is LotRule for checks that depend on the date. now is the time of CheckOptions.
+func LotRuleAt[L FeedLot](name string, check func(lot L, now time.Time, results *Results)) Rule {
+	return lotRule(name, func(lot L, run *checkRun, results *Results) {
+		check(lot, run.now, results)
+	})
+}
+
+// FeedRule makes a rule that checks whole feeds of type F, e.g. *CianFeed.
+func FeedRule[F Feed](name string, check func(feed F, results *Results)) Rule {
+	return feedRule(name, func(feed F, run *checkRun, results *Results) {
+		check(feed, results)
+	})
+}
+
+// FeedRuleAt is FeedRule for checks that depend on the date. now is the time of CheckOptions.
+func FeedRuleAt[F Feed](name string, check func(feed F, now time.Time, results *Results)) Rule {
+	return feedRule(name, func(feed F, run *checkRun, results *Results) {
+		check(feed, run.now, results)
+	})
+}
+
+func lotRule[L FeedLot](name string, check func(lot L, run *checkRun, results *Results)) Rule {
+	return Rule{
+		Name: name,
+		lot: func(lot any, run *checkRun, results *Results) {
+			if l, ok := lot.(L); ok {
+				check(l, run, results)
+			}
+		},
+		accepts: func(lot any) bool {
+			_, ok := lot.(L)
+			return ok
+		},
+	}
+}
+
+func feedRule[F Feed](name string, check func(feed F, run *checkRun, results *Results)) Rule {
+	return Rule{Name: name, feed: func(feed Feed, run *checkRun, results *Results) {
+		if f, ok := feed.(F); ok {
+			check(f, run, results)
+		}
+	}}
+}
+
+// Results collects the issues reported by a rule, attributed to the rule and, in lot rules, to the checked lot.
+type Results struct {
+	rule string
+	lots []any
+	// lot is the position of the lot checked by a lot rule, or -1 in feed rules.
+	lot    int
+	issues []Issue
+}
+
+// Add reports an issue of a field, e.g. "Ad.Price"; field may be empty. In lot rules the issue
+// concerns the checked lot, in feed rules the whole feed.
+func (r *Results) Add(field string, message string) {
+	r.AddLot(r.lot, field, message)
+}
+
+// AddLot reports an issue of the lot at position, counted over the lots of the feed in the order the lot rules
+// see them. Feed rules use it for issues of single lots, e.g. duplicates. A negative position reports a feed issue.
+func (r *Results) AddLot(position int, field string, message string) {
+	issue := Issue{Rule: r.rule, Field: issueFieldIndex.ReplaceAllString(field, "[]"), Message: message}
+	if position >= 0 && position < len(r.lots) {
+		issue.LotID = lotID(r.lots[position])
+		issue.Position = &position
+	}
+	r.issues = append(r.issues, issue)
+}
+
+func (r *Results) messages() (messages []string) {
+	for _, issue := range r.issues {
+		messages = append(messages, issue.Message)
+	}
+	return messages
+}
+
+// CheckOptions configure RuleSet.CheckWith and the CheckWith methods of the feeds.
+type CheckOptions struct {
+	// Now returns the time the date rules compare with, e.g. whether a deadline has passed. Defaults to time.Now.
+	// A future date shows the issues the feed will have by then, e.g. at the start of the next quarter.
+	Now func() time.Time
+	// MaxAge is the feed age, per platform, after which the freshness rules report a stale feed.
+	// Platforms missing from the map use DefaultMaxFeedAge.
+	MaxAge map[Platform]time.Duration
+	// Descriptions are the description rules of the platforms, e.g. DefaultDescriptionRules with stop-words.
+	// Platforms missing from the map use the default rule.
+	Descriptions map[Platform]DescriptionRule
+	// Workers is the number of goroutines the lot rules run in, on shards of lots. The issues are the same
+	// and in the same order as with one worker; lot rules must be safe for concurrent use then.
+	// Zero or one runs the rules sequentially.
+	Workers int
+}
+
+func (o CheckOptions) now() time.Time {
+	if o.Now != nil {
+		return o.Now()
+	}
+	return time.Now()
+}
+
+// checkRun is the state of one check shared by its rules.
+type checkRun struct {
+	options CheckOptions
+	now     time.Time
+	feed    Feed
+	lots    []any
+}
+
+// gateRule makes a rule that stops the check when it reports an issue, e.g. for an empty feed.
+func gateRule(rule Rule) Rule {
+	rule.gate = true
+	return rule
+}
+
+// RuleSet is a list of rules that can be enabled and disabled by name. It is safe for concurrent use.
+type RuleSet struct {
+	mu       sync.RWMutex
+	rules    []Rule
+	disabled map[string]bool
+}
+
+// DefaultRules are the rules run by the Check methods of the feeds: the built-in rules and the ones added with RegisterRule.
+var DefaultRules = NewRuleSet(builtinRules()...)
+
+func builtinRules() (rules []Rule) {
+	rules = append(rules, avitoRules...)
+	rules = append(rules, cianRules...)
+	rules = append(rules, domclickRules...)
+	rules = append(rules, realtyRules...)
+	return rules
+}
+
+// NewRuleSet returns a set of the given rules, all enabled.
+func NewRuleSet(rules ...Rule) *RuleSet {
+	s := &RuleSet{disabled: make(map[string]bool)}
+	s.Register(rules...)
+	return s
+}
+
+// RegisterRule adds rules to DefaultRules.
+func RegisterRule(rules ...Rule) {
+	DefaultRules.Register(rules...)
+}
+
+// Register adds rules to the set. A rule replaces the rule with the same name, so built-in rules can be overridden.
+// Register panics when a rule has no name or no check, e.g. a Rule literal, or when two of the rules have the same name.
+func (s *RuleSet) Register(rules ...Rule) {
+	names := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		switch {
+		case rule.Name == "":
+			panic("price_placements_feeds: rule has no name")
+		case rule.lot == nil && rule.feed == nil:
+			panic(fmt.Sprintf("price_placements_feeds: rule %s has no check, make it with LotRule or FeedRule", rule.Name))
+		case names[rule.Name]:
+			panic(fmt.Sprintf("price_placements_feeds: rule %s is registered twice", rule.Name))
+		}
+		names[rule.Name] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rule := range rules {
+		replaced := false
+		for i := range s.rules {
+			if s.rules[i].Name == rule.Name {
+				s.rules[i], replaced = rule, true
+				break
+			}
+		}
+		if !replaced {
+			s.rules = append(s.rules, rule)
+		}
+	}
+}
+
+// Disable turns off the rules matching the name patterns, e.g. "cian/phones" or "avito/*".
+func (s *RuleSet) Disable(patterns ...string) {
+	s.setDisabled(patterns, true)
+}
+
+// Enable turns on the rules matching the name patterns.
+func (s *RuleSet) Enable(patterns ...string) {
+	s.setDisabled(patterns, false)
+}
+
+func (s *RuleSet) setDisabled(patterns []string, disabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rule := range s.rules {
+		if matchAnyPattern(patterns, rule.Name) {
+			s.disabled[rule.Name] = disabled
+		}
+	}
+}
+
+// Only returns a new set with the enabled rules matching the name patterns.
+func (s *RuleSet) Only(patterns ...string) *RuleSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	subset := &RuleSet{disabled: make(map[string]bool)}
+	for _, rule := range s.rules {
+		if !s.disabled[rule.Name] && matchAnyPattern(patterns, rule.Name) {
+			subset.rules = append(subset.rules, rule)
+		}
+	}
+	return subset
+}
+
+// Names returns the sorted names of the enabled rules.
+func (s *RuleSet) Names() (names []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, rule := range s.rules {
+		if !s.disabled[rule.Name] {
+			names = append(names, rule.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check runs the enabled rules on the feed and returns the messages of the issues: gate rules first, then the
+// feed rules registered before the first lot rule for the feed, the lot rules lot by lot and the remaining
+// feed rules, each in the order of registration.
+func (s *RuleSet) Check(feed Feed) (results []string) {
+	return s.CheckWith(feed, CheckOptions{})
+}
+
+// CheckWith is Check with options, e.g. checking the feed as of another date.
+func (s *RuleSet) CheckWith(feed Feed, options CheckOptions) (results []string) {
+	for _, issue := range s.CheckIssues(feed, options) {
+		results = append(results, issue.Message)
+	}
+	return results
+}
+
+// CheckIssues is CheckWith returning the issues with the rules and lots they concern.
+func (s *RuleSet) CheckIssues(feed Feed, options CheckOptions) (issues []Issue) {
+	lots := feedLots(feed)
+	s.mu.RLock()
+	var gates, before, lotRules, after []Rule
+	for _, rule := range s.rules {
+		switch {
+		case s.disabled[rule.Name]:
+		case rule.gate:
+			gates = append(gates, rule)
+		case rule.lot != nil:
+			if len(lots) > 0 && rule.accepts(lots[0]) {
+				lotRules = append(lotRules, rule)
+			}
+		case len(lotRules) == 0:
+			before = append(before, rule)
+		default:
+			after = append(after, rule)
+		}
+	}
+	s.mu.RUnlock()
+
+	run := &checkRun{options: options, now: options.now(), feed: feed, lots: lots}
+	results := &Results{lots: run.lots, lot: -1}
+	for _, rule := range gates {
+		results.rule = rule.Name
+		rule.feed(feed, run, results)
+		if len(results.issues) > 0 {
+			return results.issues
+		}
+	}
+	for _, rule := range before {
+		results.rule = rule.Name
+		rule.feed(feed, run, results)
+	}
+	if len(lotRules) > 0 {
+		results.issues = append(results.issues, runLotRules(run, lotRules, options.Workers)...)
+	}
+	for _, rule := range after {
+		results.rule = rule.Name
+		rule.feed(feed, run, results)
+	}
+	return results.issues
+}
+
+// lotShardSize is the smallest number of lots worth a goroutine.
+const lotShardSize = 64
+
+// runLotRules runs the lot rules on every lot. With several workers the lots are split into contiguous
+// shards whose results are joined in shard order, so that they come in the same order as sequentially.
+func runLotRules(run *checkRun, rules []Rule, workers int) (issues []Issue) {
+	lots := run.lots
+	checkLots := func(from int, to int) []Issue {
+		results := &Results{lots: lots}
+		for position := from; position < to; position++ {
+			results.lot = position
+			for _, rule := range rules {
+				results.rule = rule.Name
+				rule.lot(lots[position], run, results)
+			}
+		}
+		return results.issues
+	}
+
+	shards := len(lots) / lotShardSize
+	if shards > workers*4 {
+		shards = workers * 4
+	}
+	if workers <= 1 || shards <= 1 {
+		return checkLots(0, len(lots))
+	}
+
+	shardIssues := make([][]Issue, shards)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for shard := range jobs {
+				shardIssues[shard] = checkLots(shard*len(lots)/shards, (shard+1)*len(lots)/shards)
+			}
+		}()
+	}
+	for shard := 0; shard < shards; shard++ {
+		jobs <- shard
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, shard := range shardIssues {
+		issues = append(issues, shard...)
+	}
+	return issues
+}
+
+// feedLots returns the platform-specific lots of a feed.
+func feedLots(feed Feed) (lots []any) {
+	switch f := feed.(type) {
+	case *AvitoFeed:
+		for _, ad := range f.Ad {
+			lots = append(lots, ad)
+		}
+	case *CianFeed:
+		for _, object := range f.Object {
+			lots = append(lots, object)
+		}
+	case *DomclickFeed:
+		for _, building := range f.Complex.Buildings.Building {
+			for _, flat := range building.Flats.Flat {
+				lots = append(lots, flat)
+			}
+		}
+	case *RealtyFeed:
+		for _, offer := range f.Offer {
+			lots = append(lots, offer)
+		}
+	}
+	return lots
+}
+
+// lotID returns the identifier of a platform-specific lot.
+func lotID(lot any) string {
+	switch l := lot.(type) {
+	case Ad:
+		return l.ID
+	case Object:
+		return l.ExternalId
+	case Offer:
+		return l.InternalID
+	case Flat:
+		return l.FlatID
+	}
+	return ""
+}
+
+func matchAnyPattern(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package price_placements_feeds
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testNow is the date the test feeds are generated and checked at.
+var testNow = time.Date(2024, time.May, 15, 12, 0, 0, 0, time.UTC)
+
+func testClock() time.Time {
+	return testNow
+}
+
+func testAvitoFeed(ads int) *AvitoFeed {
+	feed := &AvitoFeed{LastModified: testNow}
+	for i := 0; i < ads; i++ {
+		feed.Ad = append(feed.Ad, Ad{
+			ID:            fmt.Sprintf("ad-%d", i),
+			ContactPhone:  "+79990000000",
+			Description:   fmt.Sprintf("Участок %d. ", i) + strings.Repeat("Просторная квартира с видом на парк. ", 3),
+			Category:      string(avitoCategoryLand),
+			Price:         5000000,
+			OperationType: "Продам",
+			ObjectType:    "Поселений (ИЖС)",
+			LandArea:      10,
+			Images: struct {
+				Image []AvitoImage `xml:"Image,omitempty"`
+			}{Image: []AvitoImage{{URL: "a"}, {URL: "b"}, {URL: "c"}}},
+		})
+	}
+	return feed
+}
+
+func testDomclickFeed(buildings int, flats int) *DomclickFeed {
+	feed := &DomclickFeed{LastModified: testNow}
+	feed.Complex.ID, feed.Complex.Name, feed.Complex.Address = "c1", "ЖК Парк", "Москва"
+	feed.Complex.Latitude, feed.Complex.Longitude = "55.75", "37.61"
+	feed.Complex.DescriptionMain.Title = "ЖК Парк"
+	feed.Complex.DescriptionMain.Text = strings.Repeat("Жилой комплекс у парка с собственной школой. ", 3)
+	feed.Complex.SalesInfo.SalesPhone, feed.Complex.SalesInfo.SalesAddress = "+79990000000", "Москва"
+	feed.Complex.SalesInfo.SalesLatitude, feed.Complex.SalesInfo.SalesLongitude = "55.75", "37.61"
+	feed.Complex.Developer.Name, feed.Complex.Developer.Phone = "Девелопер", "+79990000000"
+	feed.Complex.Developer.Site, feed.Complex.Developer.Logo = "https://example.com", "https://example.com/logo.png"
+	for b := 0; b < buildings; b++ {
+		building := DomclickBuilding{
+			ID: fmt.Sprintf("b%d", b), Fz214: "1", Name: fmt.Sprintf("Корпус %d", b+1), Floors: 10,
+			BuildingState: "built", BuiltYear: 2020, ReadyQuarter: 4, BuildingType: "монолитный",
+		}
+		for i := 0; i < flats; i++ {
+			room := int64(1)
+			building.Flats.Flat = append(building.Flats.Flat, Flat{
+				FlatID: fmt.Sprintf("b%d-f%d", b, i), Floor: 2, Room: &room, Plan: "plan.png", Balcony: "нет",
+				Price: 1e7, Area: 40, LivingArea: 20, KitchenArea: 10, Bathroom: "совмещенный",
+			})
+		}
+		feed.Complex.Buildings.Building = append(feed.Complex.Buildings.Building, building)
+	}
+	return feed
+}
+
+func TestCheckIssuesReportsLotIssuesInline(t *testing.T) {
+	feed := testAvitoFeed(12)
+	feed.Ad[1].ID = ""
+	feed.Ad[1].Price = 0
+	feed.Ad[2].Price = 0
+
+	issues := DefaultRules.Only("avito/ids", "avito/required").CheckIssues(feed, CheckOptions{})
+	var got []string
+	for _, issue := range issues {
+		got = append(got, fmt.Sprintf("%s %s %s", issue.Rule, issue.Lot(), issue.Field))
+	}
+	want := []string{
+		"avito/ids #1 Ad[].ID",
+		"avito/required #1 Ad.Price",
+		"avito/required ad-2 Ad.Price",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %q, want %q", got, want)
+	}
+	if issues[0].Message != "field Ad[1].ID is empty" || *issues[2].Position != 2 || issues[2].LotID != "ad-2" {
+		t.Errorf("unexpected issue attribution: %+v", issues)
+	}
+}
+
+func TestCheckIssuesFeedRuleReportsLots(t *testing.T) {
+	feed := testAvitoFeed(12)
+	feed.Ad[5].Description = feed.Ad[3].Description
+
+	issues := DefaultRules.Only("avito/descriptions").CheckIssues(feed, CheckOptions{})
+	if len(issues) == 0 {
+		t.Fatal("duplicate description is not reported")
+	}
+	for _, issue := range issues {
+		if issue.Rule != "avito/descriptions" || issue.Field != "Ad.Description" || issue.IsFeedIssue() {
+			t.Errorf("issue %+v is not attributed to a lot", issue)
+		}
+	}
+}
+
+func TestCheckDomclickOrder(t *testing.T) {
+	feed := testDomclickFeed(2, 2)
+	feed.Complex.Name = ""
+	feed.Complex.Developer.Logo = ""
+	feed.Complex.Buildings.Building[0].Fz214 = ""
+	feed.Complex.Buildings.Building[0].Flats.Flat[1].Floor = 12
+	feed.Complex.Buildings.Building[1].Flats.Flat[0].FlatID = ""
+
+	var got []string
+	for _, issue := range DefaultRules.CheckIssues(feed, CheckOptions{Now: testClock}) {
+		got = append(got, fmt.Sprintf("%s %s", issue.Rule, issue.Lot()))
+	}
+	want := []string{
+		"domclick/complex ",
+		"domclick/buildings ",
+		"domclick/flats b0-f1",
+		"domclick/flats #2",
+		"domclick/developer ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %q, want %q", got, want)
+	}
+}
+
+func TestDomclickFlatsCheckedAgainstTheirBuilding(t *testing.T) {
+	feed := testDomclickFeed(2, 3)
+	feed.Complex.Buildings.Building[1].Floors = 1
+
+	results := DefaultRules.Only("domclick/flats").Check(feed)
+	want := []string{
+		"Field Flats.Flat.Floor is bigger than building.Floors. InternalID: b1-f0",
+		"Field Flats.Flat.Floor is bigger than building.Floors. InternalID: b1-f1",
+		"Field Flats.Flat.Floor is bigger than building.Floors. InternalID: b1-f2",
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Check() = %q, want %q", results, want)
+	}
+}
+
+func TestRuleSetRegisterDisableOnly(t *testing.T) {
+	rules := NewRuleSet(avitoRules...)
+	rules.Register(LotRule("avito/price-round", func(lot Ad, results *Results) {
+		if lot.Price%1000 != 0 {
+			results.Add("Ad.Price", fmt.Sprintf("field Ad.Price is not rounded. InternalID: %s", lot.ID))
+		}
+	}))
+	feed := testAvitoFeed(12)
+	feed.Ad[4].Price = 1234567
+
+	issues := rules.Only("avito/price-round").CheckIssues(feed, CheckOptions{})
+	if len(issues) != 1 || issues[0].Rule != "avito/price-round" || issues[0].LotID != "ad-4" {
+		t.Errorf("custom rule issues = %+v", issues)
+	}
+
+	rules.Disable("avito/*")
+	if names := rules.Names(); len(names) != 0 {
+		t.Errorf("Names() after Disable = %q", names)
+	}
+	if results := rules.Check(feed); results != nil {
+		t.Errorf("Check() with all rules disabled = %q", results)
+	}
+	rules.Enable("avito/price-round")
+	if names := rules.Names(); !reflect.DeepEqual(names, []string{"avito/price-round"}) {
+		t.Errorf("Names() after Enable = %q", names)
+	}
+}
+
+func TestRuleSetRegisterRejectsInvalidRules(t *testing.T) {
+	check := LotRule("avito/check", func(lot Ad, results *Results) {})
+	tests := []struct {
+		name  string
+		rules []Rule
+		want  string
+	}{
+		{"literal", []Rule{{Name: "avito/literal"}}, "rule avito/literal has no check"},
+		{"no name", []Rule{LotRule("", func(lot Ad, results *Results) {})}, "rule has no name"},
+		{"duplicate", []Rule{check, check}, "rule avito/check is registered twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := NewRuleSet(check)
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), tt.want) {
+					t.Errorf("Register() panic = %v, want %q", r, tt.want)
+				}
+				if names := rules.Names(); !reflect.DeepEqual(names, []string{"avito/check"}) {
+					t.Errorf("rules after a failed Register() = %q", names)
+				}
+			}()
+			rules.Register(tt.rules...)
+		})
+	}
+
+	// A rule replaces the registered rule with its name.
+	rules := NewRuleSet(check)
+	rules.Register(check)
+	if names := rules.Names(); !reflect.DeepEqual(names, []string{"avito/check"}) {
+		t.Errorf("Names() = %q", names)
+	}
+}
+
+func TestGateRuleStopsCheck(t *testing.T) {
+	feed := testAvitoFeed(5)
+	feed.Ad[0].Price = 0
+	results := DefaultRules.Check(feed)
+	if !reflect.DeepEqual(results, []string{"feed contains only 5 items"}) {
+		t.Errorf("Check() = %q", results)
+	}
+}
+
+func TestParallelCheckMatchesSequential(t *testing.T) {
+	domclick := testDomclickFeed(5, 60)
+	for b := range domclick.Complex.Buildings.Building {
+		building := &domclick.Complex.Buildings.Building[b]
+		building.Floors = int64(b + 2)
+		for i := range building.Flats.Flat {
+			building.Flats.Flat[i].Floor = int64(i % 8)
+			if i%7 == 0 {
+				building.Flats.Flat[i].Plan = ""
+			}
+		}
+	}
+	avito := testAvitoFeed(500)
+	for i := range avito.Ad {
+		if i%3 == 0 {
+			avito.Ad[i].Price = 0
+		}
+		if i%11 == 0 {
+			avito.Ad[i].ID = ""
+		}
+	}
+
+	for _, feed := range []Feed{domclick, avito} {
+		sequential := DefaultRules.CheckIssues(feed, CheckOptions{})
+		if len(sequential) == 0 {
+			t.Fatalf("%T: no issues", feed)
+		}
+		for _, workers := range []int{2, 4, 16} {
+			parallel := DefaultRules.CheckIssues(feed, CheckOptions{Workers: workers})
+			if !reflect.DeepEqual(parallel, sequential) {
+				t.Errorf("%T: issues with %d workers differ from the sequential check", feed, workers)
+			}
+		}
+	}
+}
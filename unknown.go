@@ -0,0 +1,56 @@
+package price_placements_feeds
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// UnknownElement keeps an element that is not modelled by the feed structs,
+// so that Marshal and WriteTo write it back unchanged.
+type UnknownElement struct {
+	XMLName  xml.Name
+	Attr     []xml.Attr `xml:",any,attr"`
+	InnerXML string     `xml:",innerxml"`
+}
+
+// MarshalXML writes the element in the namespace it was read in, with the prefix when the element declares it.
+// A namespace that repeats the default namespace of the parent is left out by marshalFeed.
+func (u UnknownElement) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type rawElement UnknownElement
+	raw := rawElement(u)
+	for _, attr := range u.Attr {
+		if attr.Name.Space == "xmlns" && attr.Value == u.XMLName.Space {
+			raw.XMLName = xml.Name{Local: attr.Name.Local + ":" + u.XMLName.Local}
+			return e.Encode(raw)
+		}
+	}
+	for _, attr := range u.Attr {
+		if attr.Name.Space == "" && attr.Name.Local == "xmlns" && attr.Value == u.XMLName.Space {
+			// The xmlns attribute declares the namespace.
+			raw.XMLName.Space = ""
+		}
+	}
+	return e.Encode(raw)
+}
+
+// checkUnknownWithID reports unknown elements and attributes of the lot at position, or of the feed for a negative position.
+func checkUnknownWithID(ID string, position int, path string, elements []UnknownElement, attrs []xml.Attr, results *Results) {
+	var idMessage string
+	if ID == "" {
+		idMessage = "InternalID not found"
+	} else {
+		idMessage = fmt.Sprintf("InternalID: %s", ID)
+	}
+
+	for _, element := range elements {
+		field := path + "." + element.XMLName.Local
+		results.AddLot(position, field, fmt.Sprintf("unknown element %s. %s", field, idMessage))
+	}
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		field := path + "@" + xmlNodeName(attr.Name)
+		results.AddLot(position, field, fmt.Sprintf("unknown attribute %s. %s", field, idMessage))
+	}
+}
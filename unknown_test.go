@@ -0,0 +1,78 @@
+package price_placements_feeds
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckUnknown(t *testing.T) {
+	data := `<realty-feed xmlns="http://webmaster.yandex.ru/schemas/feed/realty/2010-06" version="2">
+  <offer internal-id="offer-1" promo="top">
+    <type>продажа</type>
+    <livng-space><value>30</value></livng-space>
+  </offer>
+  <generator>crm</generator>
+</realty-feed>`
+	var feed RealtyFeed
+	if err := xml.Unmarshal([]byte(data), &feed); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"unknown element realty-feed.generator. InternalID not found",
+		"unknown attribute realty-feed@version. InternalID not found",
+		"unknown element offer.livng-space. InternalID: offer-1",
+		"unknown attribute offer@promo. InternalID: offer-1",
+	}
+	if got := feed.CheckUnknown(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckUnknown() = %q, want %q", got, want)
+	}
+}
+
+func TestStrictModeReportsUnknown(t *testing.T) {
+	data := `<complexes><complex><id>c1</id><buildings><building><id>b1</id><flats>` +
+		`<flat><flat_id>f1</flat_id></flat><flat><flat_id>f2</flat_id><promo>top</promo></flat>` +
+		`</flats></building></buildings></complex></complexes>`
+	var feed DomclickFeed
+	if err := xml.Unmarshal([]byte(data), &feed); err != nil {
+		t.Fatal(err)
+	}
+
+	if issues := DefaultRules.Only("domclick/unknown").CheckIssues(&feed, CheckOptions{}); len(issues) != 0 {
+		t.Errorf("unknown elements are reported without strict mode: %+v", issues)
+	}
+
+	feed.Strict = true
+	issues := DefaultRules.Only("domclick/unknown").CheckIssues(&feed, CheckOptions{})
+	if len(issues) != 1 {
+		t.Fatalf("issues = %+v", issues)
+	}
+	if issue := issues[0]; issue.LotID != "f2" || issue.Field != "Flats.Flat.promo" || issue.Position == nil || *issue.Position != 1 {
+		t.Errorf("issue = %+v", issue)
+	}
+}
+
+func TestDiscardUnknown(t *testing.T) {
+	var feed AvitoFeed
+	data := `<Ads formatVersion="3"><Ad kind="x"><Id>ad-1</Id><Promo>x2</Promo></Ad><Generator>crm</Generator></Ads>`
+	if err := xml.Unmarshal([]byte(data), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.CheckUnknown()) != 3 {
+		t.Fatalf("CheckUnknown() = %q", feed.CheckUnknown())
+	}
+
+	feed.DiscardUnknown()
+	if results := feed.CheckUnknown(); len(results) != 0 {
+		t.Errorf("CheckUnknown() after DiscardUnknown = %q", results)
+	}
+	out, err := feed.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "Promo") || strings.Contains(string(out), "Generator") || !strings.Contains(string(out), "ad-1") {
+		t.Errorf("Marshal() = %s", out)
+	}
+}
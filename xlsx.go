@@ -0,0 +1,175 @@
+package price_placements_feeds
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationship []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+// xlsxText is a shared or inline string, either plain or split into rich text runs.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Number int `xml:"r,attr"`
+		Cells  []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSXRows returns the rows of the first worksheet of an XLSX file. Rows without cells may be left out
+// of the file, so the row numbers are taken from their references.
+func readXLSXRows(r io.ReaderAt, size int64) (rows []inventoryRow, err error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var workbook xlsxWorkbook
+	if err := readXLSXPart(archive, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, fmt.Errorf("xlsx: workbook has no sheets")
+	}
+
+	var rels xlsxRelationships
+	if err := readXLSXPart(archive, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	sheetPath := ""
+	for _, rel := range rels.Relationship {
+		if rel.ID == workbook.Sheets[0].RID {
+			sheetPath = rel.Target
+			if strings.HasPrefix(sheetPath, "/") {
+				sheetPath = strings.TrimPrefix(sheetPath, "/")
+			} else {
+				sheetPath = path.Join("xl", sheetPath)
+			}
+		}
+	}
+	if sheetPath == "" {
+		return nil, fmt.Errorf("xlsx: sheet '%s' is not found", workbook.Sheets[0].Name)
+	}
+
+	var shared xlsxSharedStrings
+	if err := readXLSXPart(archive, "xl/sharedStrings.xml", &shared); err != nil && err != errXLSXPartNotFound {
+		return nil, err
+	}
+
+	var sheet xlsxSheet
+	if err := readXLSXPart(archive, sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+
+	number := 0
+	for _, row := range sheet.Rows {
+		number++
+		if row.Number > 0 {
+			number = row.Number
+		}
+		var values []string
+		for idx, cell := range row.Cells {
+			column := idx
+			if cell.Ref != "" {
+				column, err = xlsxColumnIndex(cell.Ref)
+				if err != nil {
+					return nil, err
+				}
+			}
+			for len(values) <= column {
+				values = append(values, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				i, err := strconv.Atoi(cell.Value)
+				if err != nil || i < 0 || i >= len(shared.Items) {
+					return nil, fmt.Errorf("xlsx: cell %s refers to unknown shared string '%s'", cell.Ref, cell.Value)
+				}
+				values[column] = shared.Items[i].String()
+			case "inlineStr":
+				values[column] = cell.Inline.String()
+			case "b":
+				values[column] = map[string]string{"0": "false", "1": "true"}[cell.Value]
+			default:
+				values[column] = cell.Value
+			}
+		}
+		rows = append(rows, inventoryRow{number: number, values: values})
+	}
+	return rows, nil
+}
+
+var errXLSXPartNotFound = fmt.Errorf("xlsx: part is not found")
+
+func readXLSXPart(archive *zip.Reader, name string, v any) error {
+	for _, file := range archive.File {
+		if file.Name != name {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return xml.NewDecoder(rc).Decode(v)
+	}
+	return errXLSXPartNotFound
+}
+
+// xlsxColumnIndex converts a cell reference like "AB12" into a zero-based column index.
+// Sheets have at most three column letters, up to "XFD".
+func xlsxColumnIndex(ref string) (int, error) {
+	letters := strings.IndexFunc(ref, func(r rune) bool { return r < 'A' || r > 'Z' })
+	if letters <= 0 || letters > 3 || strings.Trim(ref[letters:], "0123456789") != "" {
+		return 0, fmt.Errorf("xlsx: invalid cell reference '%s'", ref)
+	}
+	column := 0
+	for _, r := range ref[:letters] {
+		column = column*26 + int(r-'A'+1)
+	}
+	return column - 1, nil
+}